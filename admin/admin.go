@@ -0,0 +1,195 @@
+// Package admin provides http.Handler which allows to view and change log4g
+// settings at runtime. The handler can be mounted on a debug port like:
+//
+//	http.Handle("/log4g/", http.StripPrefix("/log4g", admin.NewHandler()))
+//
+// The handler serves the following requests, the paths are relative to the
+// mount point:
+//
+//	GET  /           - loggers, effective config and statistics
+//	GET  /loggers    - loggers with their levels, contexts and appenders
+//	GET  /config     - the configuration properties in force
+//	GET  /stats      - contexts and appenders statistics
+//	PUT  /level      - sets the logger level, the body is {"logger": "a.b", "level": "DEBUG"}
+//	                   with optional "duration": "15m" to set the level temporarily
+//	DELETE /level?logger=a.b - removes the logger level, so it is inherited again
+//	PUT  /levelName  - sets the level name, the body is {"level": 11, "name": "SEVERE"}
+//	PUT  /config     - applies the config properties, the body is {"key": "value", ...}
+//
+// POST can be used instead of PUT. Responses are JSON documents, errors are
+// reported as {"error": "..."} with 4xx status.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dspasibenko/log4g"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State is the response to GET / request
+type State struct {
+	Loggers []log4g.LoggerInfo
+	Config  map[string]string
+	Stats   *log4g.Statistics
+}
+
+// LevelRequest is the body of PUT /level request. Level is either the level
+// name or its number. The JSON field names are case-insensitive
+type LevelRequest struct {
+	Logger   string
+	Level    json.RawMessage
+	Duration string
+}
+
+// LevelNameRequest is the body of PUT /levelName request
+type LevelNameRequest struct {
+	Level log4g.Level
+	Name  string
+}
+
+type handler struct {
+}
+
+// NewHandler returns the handler of log4g admin requests
+func NewHandler() http.Handler {
+	return &handler{}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveGet(w, path)
+	case http.MethodPut, http.MethodPost:
+		h.servePut(w, r, path)
+	case http.MethodDelete:
+		if path != "level" {
+			writeError(w, http.StatusNotFound, errors.New("unknown path /"+path))
+			return
+		}
+		logger := r.URL.Query().Get("logger")
+		writeJSON(w, http.StatusOK, map[string]bool{"reset": log4g.ResetLogLevel(logger)})
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method "+r.Method+" is not allowed"))
+	}
+}
+
+func (h *handler) serveGet(w http.ResponseWriter, path string) {
+	switch path {
+	case "":
+		writeJSON(w, http.StatusOK, &State{Loggers: log4g.Loggers(), Config: log4g.EffectiveConfig(),
+			Stats: log4g.Stats()})
+	case "loggers":
+		writeJSON(w, http.StatusOK, log4g.Loggers())
+	case "config":
+		writeJSON(w, http.StatusOK, log4g.EffectiveConfig())
+	case "stats":
+		writeJSON(w, http.StatusOK, log4g.Stats())
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown path /"+path))
+	}
+}
+
+func (h *handler) servePut(w http.ResponseWriter, r *http.Request, path string) {
+	var err error
+	switch path {
+	case "level":
+		err = setLevel(r)
+	case "levelName":
+		err = setLevelName(r)
+	case "config":
+		err = setConfig(r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown path /"+path))
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.serveGet(w, "loggers")
+}
+
+func setLevel(r *http.Request) error {
+	var req LevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.New("incorrect request body: " + err.Error())
+	}
+	level, err := parseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+
+	if req.Duration == "" {
+		log4g.SetLogLevel(req.Logger, level)
+		return nil
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		return errors.New("incorrect duration \"" + req.Duration + "\": " + err.Error())
+	}
+	return log4g.SetLogLevelFor(req.Logger, level, d)
+}
+
+// parseLevel accepts the level number or the level name
+func parseLevel(value json.RawMessage) (log4g.Level, error) {
+	var name string
+	if err := json.Unmarshal(value, &name); err != nil {
+		n, err := strconv.Atoi(string(value))
+		if err != nil {
+			return 0, errors.New("incorrect level " + string(value) + ", expected the level name or number")
+		}
+		name = strconv.Itoa(n)
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 0 || n > int(log4g.ALL) {
+			return 0, errors.New("incorrect level " + name + ", expected number in [0.." +
+				strconv.Itoa(int(log4g.ALL)) + "]")
+		}
+		return log4g.Level(n), nil
+	}
+	level, ok := log4g.GetLevelByName(name)
+	if !ok {
+		return 0, errors.New("unknown level \"" + name + "\"")
+	}
+	return level, nil
+}
+
+func setLevelName(r *http.Request) error {
+	var req LevelNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.New("incorrect request body: " + err.Error())
+	}
+	if !log4g.SetLogLevelName(req.Level, req.Name) {
+		return errors.New("incorrect level " + strconv.Itoa(int(req.Level)) + ", expected number in [0.." +
+			strconv.Itoa(int(log4g.ALL)) + "]")
+	}
+	return nil
+}
+
+func setConfig(r *http.Request) error {
+	var props map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&props); err != nil {
+		return errors.New("incorrect request body: " + err.Error())
+	}
+	return log4g.Config(props)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/dspasibenko/log4g"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type adminSuite struct {
+}
+
+var _ = Suite(&adminSuite{})
+
+func (s *adminSuite) SetUpTest(c *C) {
+	c.Assert(log4g.Config(map[string]string{"appender.ROOT.type": "log4g/consoleAppender",
+		"appender.ROOT.layout": "%p %c: %m", "context.appenders": "ROOT", "context.level": "INFO"}), IsNil)
+}
+
+func serve(method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func decode(c *C, w *httptest.ResponseRecorder, v interface{}) {
+	c.Assert(w.Header().Get("Content-Type"), Equals, "application/json")
+	c.Assert(json.Unmarshal(w.Body.Bytes(), v), IsNil)
+}
+
+func (s *adminSuite) TestGet(c *C) {
+	log4g.GetLogger("a.b")
+
+	w := serve(http.MethodGet, "/", "")
+	c.Assert(w.Code, Equals, http.StatusOK)
+	var state State
+	decode(c, w, &state)
+	c.Assert(state.Config["context.appenders"], Equals, "ROOT")
+	c.Assert(len(state.Stats.Appenders), Equals, 1)
+	found := false
+	for _, li := range state.Loggers {
+		if li.Name == "a.b" {
+			found = true
+			c.Assert(li.Level, Equals, log4g.INFO)
+			c.Assert(li.Appenders, DeepEquals, []string{"ROOT"})
+		}
+	}
+	c.Assert(found, Equals, true)
+
+	w = serve(http.MethodGet, "/config/", "")
+	c.Assert(w.Code, Equals, http.StatusOK)
+	var props map[string]string
+	decode(c, w, &props)
+	c.Assert(props["appender.ROOT.layout"], Equals, "%p %c: %m")
+
+	c.Assert(serve(http.MethodGet, "/unknown", "").Code, Equals, http.StatusNotFound)
+	c.Assert(serve(http.MethodPatch, "/", "").Code, Equals, http.StatusMethodNotAllowed)
+}
+
+func (s *adminSuite) TestSetLevel(c *C) {
+	w := serve(http.MethodPut, "/level", `{"logger": "a.b", "level": "debug"}`)
+	c.Assert(w.Code, Equals, http.StatusOK)
+	checkLevel(c, "a.b", log4g.DEBUG, true)
+
+	c.Assert(serve(http.MethodPost, "/level", `{"logger": "a.b", "level": 60}`).Code, Equals, http.StatusOK)
+	checkLevel(c, "a.b", log4g.TRACE, true)
+
+	c.Assert(serve(http.MethodPut, "/level", `{"logger": "a.b.c", "level": "WARN", "duration": "1h"}`).Code,
+		Equals, http.StatusOK)
+	checkLevel(c, "a.b.c", log4g.WARN, true)
+
+	c.Assert(serve(http.MethodDelete, "/level?logger=a.b", "").Code, Equals, http.StatusOK)
+	checkLevel(c, "a.b", log4g.INFO, false)
+
+	var resp map[string]string
+	w = serve(http.MethodPut, "/level", `{"logger": "a.b", "level": "LOUD"}`)
+	c.Assert(w.Code, Equals, http.StatusBadRequest)
+	decode(c, w, &resp)
+	c.Assert(resp["error"], Equals, "unknown level \"LOUD\"")
+	c.Assert(serve(http.MethodPut, "/level", `{"logger": "a.b", "level": 100}`).Code, Equals, http.StatusBadRequest)
+	c.Assert(serve(http.MethodPut, "/level", `{"logger": "a.b", "level": "INFO", "duration": "1"}`).Code,
+		Equals, http.StatusBadRequest)
+	c.Assert(serve(http.MethodPut, "/level", `{"logger"`).Code, Equals, http.StatusBadRequest)
+}
+
+func checkLevel(c *C, loggerName string, level log4g.Level, explicit bool) {
+	l, e := log4g.GetLogLevel(loggerName)
+	c.Assert(l, Equals, level)
+	c.Assert(e, Equals, explicit)
+}
+
+func (s *adminSuite) TestSetLevelName(c *C) {
+	c.Assert(serve(http.MethodPut, "/levelName", `{"level": 11, "name": "SEVERE"}`).Code, Equals, http.StatusOK)
+	c.Assert(serve(http.MethodPut, "/level", `{"logger": "a", "level": "severe"}`).Code, Equals, http.StatusOK)
+	checkLevel(c, "a", 11, true)
+
+	c.Assert(serve(http.MethodPut, "/levelName", `{"level": 100, "name": "HUGE"}`).Code, Equals,
+		http.StatusBadRequest)
+}
+
+func (s *adminSuite) TestSetConfig(c *C) {
+	w := serve(http.MethodPut, "/config", `{"appender.ROOT.type": "log4g/consoleAppender",
+		"appender.ROOT.layout": "%m", "context.appenders": "ROOT", "context.level": "WARN"}`)
+	c.Assert(w.Code, Equals, http.StatusOK)
+	checkLevel(c, "", log4g.WARN, true)
+	c.Assert(log4g.EffectiveConfig()["appender.ROOT.layout"], Equals, "%m")
+
+	w = serve(http.MethodPut, "/config", `{"context.appenders": "UNKNOWN"}`)
+	c.Assert(w.Code, Equals, http.StatusBadRequest)
+	checkLevel(c, "", log4g.WARN, true)
+}
@@ -0,0 +1,64 @@
+package log4g
+
+import (
+	"github.com/dspasibenko/log4g/collections"
+	"runtime"
+)
+
+// number of frames between a Logger method called by user and the
+// captureCaller() function:
+// Logger.<method> -> logger.<internal method> -> logger.logInternal -> captureCaller
+const callerSkip = 3
+
+// callerSetting keeps whether caller capturing is enabled for the loggerName
+// and all its descendants
+type callerSetting struct {
+	loggerName string
+	enabled    bool
+}
+
+// captureCaller returns the source code location skip frames above the caller
+// of the function
+func captureCaller(skip int) *CallerInfo {
+	pc := make([]uintptr, 1)
+	if runtime.Callers(skip+2, pc) == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames(pc).Next()
+	return &CallerInfo{frame.File, frame.Line, frame.Function}
+}
+
+/**
+ * Stores caller setting for the provided loggerName
+ * Params:
+ *		loggerName - should be eligable normalized logger name
+ */
+func setCallerSetting(enabled bool, loggerName string, callers *collections.SortedSlice) *callerSetting {
+	cs := &callerSetting{loggerName, enabled}
+	idx, found := callers.Find(cs)
+	if found {
+		cs = callers.At(idx).(*callerSetting)
+		cs.enabled = enabled
+	} else {
+		callers.Add(cs)
+	}
+	return cs
+}
+
+func getCallerSetting(loggerName string, callers *collections.SortedSlice) *callerSetting {
+	cProvider := getNearestAncestor(&callerSetting{loggerName: loggerName}, callers)
+	if cProvider == nil {
+		return nil
+	}
+	return cProvider.(*callerSetting)
+}
+
+// logNameProvider implementation
+func (cs *callerSetting) name() string {
+	return cs.loggerName
+}
+
+// Comparator implementation
+func (cs *callerSetting) Compare(other collections.Comparator) int {
+	return compare(cs, other.(*callerSetting))
+}
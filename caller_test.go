@@ -0,0 +1,127 @@
+package log4g
+
+import (
+	"github.com/dspasibenko/log4g/collections"
+	. "gopkg.in/check.v1"
+	"runtime"
+	"time"
+)
+
+type callerSuite struct {
+}
+
+var _ = Suite(&callerSuite{})
+
+func (s *callerSuite) TestCaptureCallerAllMethods(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: TRACE, caller: true})
+	calls := []func() int{
+		func() int { l.Fatal("m"); return currentLine() },
+		func() int { l.Error("m"); return currentLine() },
+		func() int { l.Warn("m"); return currentLine() },
+		func() int { l.Info("m"); return currentLine() },
+		func() int { l.Debug("m"); return currentLine() },
+		func() int { l.Trace("m"); return currentLine() },
+		func() int { l.Log(INFO, "m"); return currentLine() },
+		func() int { l.Logf(INFO, "m %d", 1); return currentLine() },
+		func() int { l.Logp(INFO, "m"); return currentLine() },
+		func() int { l.Fatalw("m", "k", 1); return currentLine() },
+		func() int { l.Errorw("m", "k", 1); return currentLine() },
+		func() int { l.Warnw("m", "k", 1); return currentLine() },
+		func() int { l.Infow("m", "k", 1); return currentLine() },
+		func() int { l.Debugw("m", "k", 1); return currentLine() },
+		func() int { l.Tracew("m", "k", 1); return currentLine() },
+		func() int { l.Logw(INFO, "m", "k", 1); return currentLine() },
+		func() int { l.With("k", 1).Info("m"); return currentLine() },
+		func() int { l.InfoCtx(nil, "m"); return currentLine() },
+		func() int { l.LogCtx(nil, INFO, "m"); return currentLine() },
+		func() int { l.LogfCtx(nil, INFO, "m %d", 1); return currentLine() },
+		func() int { l.LogwCtx(nil, INFO, "m", "k", 1); return currentLine() },
+	}
+
+	_, file, _, _ := runtime.Caller(0)
+	for _, call := range calls {
+		line := call()
+		le := <-lctx.eventsCh
+		c.Assert(le.Caller, NotNil)
+		c.Assert(le.Caller.File, Equals, file)
+		c.Assert(le.Caller.Line, Equals, line)
+	}
+}
+
+func (s *callerSuite) TestCaptureCallerDisabled(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
+	l.Info("m")
+	c.Assert((<-lctx.eventsCh).Caller, IsNil)
+}
+
+func (s *callerSuite) TestWithCallerSkip(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO, caller: true})
+	wrapper := func(msg string) {
+		l.WithCallerSkip(1).Info(msg)
+	}
+	wrapper("m")
+	line := currentLine() - 1
+	le := <-lctx.eventsCh
+	c.Assert(le.Caller.Line, Equals, line)
+	c.Assert(le.Caller.Function, Equals, "github.com/dspasibenko/log4g.(*callerSuite).TestWithCallerSkip")
+}
+
+func (s *callerSuite) TestGetSetCallerSetting(c *C) {
+	ss, _ := collections.NewSortedSlice(2)
+	c.Assert(getCallerSetting("a", ss), IsNil)
+
+	setCallerSetting(true, "a.b", ss)
+	setCallerSetting(false, "a.b.c", ss)
+	c.Assert(getCallerSetting("a", ss), IsNil)
+	c.Assert(getCallerSetting("a.b.d", ss).enabled, Equals, true)
+	c.Assert(getCallerSetting("a.b.c.d", ss).enabled, Equals, false)
+
+	setCallerSetting(true, "a.b.c", ss)
+	c.Assert(ss.Len(), Equals, 2)
+	c.Assert(getCallerSetting("a.b.c.d", ss).enabled, Equals, true)
+}
+
+func (s *callerSuite) TestCallerConfig(c *C) {
+	lc := newLogConfig()
+	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	lc.setConfigParams(map[string]string{
+		"appender.ROOT.type":  consoleAppenderName,
+		"context.appenders":   "ROOT",
+		"context.a.appenders": "ROOT",
+		"context.a.caller":    "true",
+		"logger.b.caller":     "true",
+		"logger.b.c.caller":   "false",
+		"logger.b.c.level":    "DEBUG",
+		"logger.d.level":      "DEBUG"})
+
+	c.Assert(lc.getLogger("a.b").(*logger).getState().caller, Equals, true)
+	c.Assert(lc.getLogger("b").(*logger).getState().caller, Equals, true)
+	c.Assert(lc.getLogger("b.c").(*logger).getState().caller, Equals, false)
+	c.Assert(lc.getLogger("d").(*logger).getState().caller, Equals, false)
+	// the caller setting doesn't set the logger level
+	c.Assert(getLogLevelSetting("b", lc.logLevels).loggerName, Equals, rootLoggerName)
+
+	pnc := checkPanic(func() { lc.createLoggers(map[string]string{"logger.b.caller": "yes"}) })
+	c.Assert(pnc, Equals, true)
+}
+
+func (s *callerSuite) TestCallerLayout(c *C) {
+	t, _ := ParseLayout("%F:%L %M")
+	c.Assert(len(t), Equals, 5)
+	c.Assert(t[0].pieceType, Equals, lpCallerFile)
+	c.Assert(t[2].pieceType, Equals, lpCallerLine)
+	c.Assert(t[4].pieceType, Equals, lpCallerFunction)
+
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+	c.Assert(ToLogMessage(le, t), Equals, ": ")
+	le.Caller = &CallerInfo{"/src/a/b.go", 12, "a.b.F"}
+	c.Assert(ToLogMessage(le, t), Equals, "b.go:12 a.b.F")
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
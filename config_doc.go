@@ -0,0 +1,201 @@
+package log4g
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// docSections maps the top level sections of JSON and YAML config documents
+// to the config objects. For example, the document
+//
+//	appenders:
+//	  file: {type: log4g/fileAppender, fileName: app.log}
+//	contexts:
+//	  "": {appenders: [file]}
+//	  a.b: {level: DEBUG}
+//
+// is mapped to appender.file.type, appender.file.fileName, context.appenders
+// and context.a.b.level properties. "" is the root logger name.
+var docSections = map[string]string{"levels": cfgLevel, "appenders": cfgAppender, "contexts": cfgContext,
+	"loggers": cfgLogger}
+
+// docDecoders are the decoders of the config files by their lower case
+// extensions, other files are read as properties
+var (
+	docDecoders     = map[string]ConfigDecoder{".json": parseJSONDoc}
+	docDecodersLock sync.RWMutex
+)
+
+func registerConfigDecoder(decoder ConfigDecoder, exts []string) {
+	docDecodersLock.Lock()
+	defer docDecodersLock.Unlock()
+
+	for _, ext := range exts {
+		docDecoders[strings.ToLower(ext)] = decoder
+	}
+}
+
+// returns the decoder of the file by its extension, or nil if the file is
+// read as properties
+func getConfigDecoder(configFileName string) ConfigDecoder {
+	docDecodersLock.RLock()
+	defer docDecodersLock.RUnlock()
+
+	return docDecoders[strings.ToLower(filepath.Ext(configFileName))]
+}
+
+// configures by the file, which format is detected by its extension
+func (lm *logManager) setConfigFile(configFileName string) error {
+	if decoder := getConfigDecoder(configFileName); decoder != nil {
+		return lm.setConfigDoc(configFileName, decoder)
+	}
+	return lm.setPropsFromFile(configFileName)
+}
+
+func (lm *logManager) setConfigDoc(configFileName string, decoder ConfigDecoder) error {
+	f, err := os.Open(configFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return lm.setConfigReader(f, decoder)
+}
+
+func (lm *logManager) setConfigReader(r io.Reader, decoder ConfigDecoder) error {
+	doc, err := decoder(r)
+	if err != nil {
+		return err
+	}
+	paths := make(map[string]string)
+	props, err := docToProps(doc, paths)
+	if err != nil {
+		return err
+	}
+	if err = lm.setNewProperties(props); err != nil {
+		return docPropsError(err, lm.validate(props, nil), paths)
+	}
+	return nil
+}
+
+// docPropsError returns the errors of all document elements, which properties
+// are reported by the config validation, because the one which failed the
+// config is not known. It returns err if none of the elements is known
+func docPropsError(err error, errs []ConfigError, paths map[string]string) error {
+	var docErrs []error
+	for _, ce := range errs {
+		if path, ok := paths[ce.Key]; ok {
+			docErrs = append(docErrs, docError(path, ce.Msg))
+		}
+	}
+	if len(docErrs) == 0 {
+		return err
+	}
+	return errors.Join(docErrs...)
+}
+
+func parseJSONDoc(r io.Reader) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.New("Cannot parse JSON config: " + err.Error())
+	}
+	return doc, nil
+}
+
+// docToProps maps the document to the flat config properties. Errors refer
+// to the document elements by their paths like /contexts/a.b/buffer. The
+// paths of the properties are added to paths if it is not nil
+func docToProps(doc interface{}, paths map[string]string) (map[string]string, error) {
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, docError("", "the document should be an object with levels, appenders, contexts or loggers")
+	}
+
+	props := make(map[string]string)
+	for _, section := range sortedKeys(root) {
+		path := "/" + section
+		object, ok := docSections[section]
+		if !ok {
+			return nil, docError(path, "unknown section, expected levels, appenders, contexts or loggers")
+		}
+		names, ok := root[section].(map[string]interface{})
+		if !ok {
+			return nil, docError(path, "object is expected")
+		}
+
+		for _, name := range sortedKeys(names) {
+			namePath := path + "/" + name
+			if object == cfgLevel {
+				value, err := docValue(namePath, names[name])
+				if err != nil {
+					return nil, err
+				}
+				props[cfgLevel+"."+name] = value
+				if paths != nil {
+					paths[cfgLevel+"."+name] = namePath
+				}
+				continue
+			}
+
+			attrs, ok := names[name].(map[string]interface{})
+			if !ok {
+				return nil, docError(namePath, "object is expected")
+			}
+			for _, attr := range sortedKeys(attrs) {
+				value, err := docValue(namePath+"/"+attr, attrs[attr])
+				if err != nil {
+					return nil, err
+				}
+				props[configKey(object, name, attr)] = value
+				if paths != nil {
+					paths[configKey(object, name, attr)] = namePath + "/" + attr
+				}
+			}
+		}
+	}
+	return props, nil
+}
+
+// docValue returns the string value of the scalar, or comma separated values
+// of the list of scalars
+func docValue(path string, v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case json.Number:
+		return val.String(), nil
+	case []interface{}:
+		values := make([]string, len(val))
+		for i, e := range val {
+			if _, ok := e.([]interface{}); ok {
+				return "", docError(path+"/"+strconv.Itoa(i), "nested lists are not supported")
+			}
+			s, err := docValue(path+"/"+strconv.Itoa(i), e)
+			if err != nil {
+				return "", err
+			}
+			values[i] = s
+		}
+		return strings.Join(values, ","), nil
+	}
+	return "", docError(path, "scalar value or list of scalars is expected")
+}
+
+func docError(path, msg string) error {
+	if path == "" {
+		path = "/"
+	}
+	return errors.New("Incorrect config document element " + path + ": " + msg)
+}
@@ -0,0 +1,126 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type configDocSuite struct {
+}
+
+var _ = Suite(&configDocSuite{})
+
+var expectedDocProps = map[string]string{
+	"level.11":             "SEVERE",
+	"appender.ROOT.type":   consoleAppenderName,
+	"appender.ROOT.layout": "%p %m",
+	"context.appenders":    "ROOT",
+	"context.a.b.level":    "DEBUG",
+	"context.a.b.buffer":   "1000",
+	"context.a.b.blocking": "false",
+	"logger.a.b.c.level":   "TRACE"}
+
+func (s *configDocSuite) TestJSON(c *C) {
+	doc, err := parseJSONDoc(strings.NewReader(`{
+		"levels":    {"11": "SEVERE"},
+		"appenders": {"ROOT": {"type": "log4g/consoleAppender", "layout": "%p %m"}},
+		"contexts":  {"": {"appenders": ["ROOT"]}, "a.b": {"level": "DEBUG", "buffer": 1000, "blocking": false}},
+		"loggers":   {"a.b.c": {"level": "TRACE"}}
+	}`))
+	c.Assert(err, IsNil)
+	props, err := docToProps(doc, nil)
+	c.Assert(err, IsNil)
+	c.Assert(props, DeepEquals, expectedDocProps)
+
+	_, err = parseJSONDoc(strings.NewReader(`{"levels": `))
+	c.Assert(err, ErrorMatches, "Cannot parse JSON config: .*")
+}
+
+func (s *configDocSuite) TestConfigDecoders(c *C) {
+	c.Assert(getConfigDecoder("log4g.JSON"), NotNil)
+	c.Assert(getConfigDecoder("log4g.properties"), IsNil)
+	c.Assert(getConfigDecoder("log4g"), IsNil)
+
+	var decoded string
+	registerConfigDecoder(func(r io.Reader) (interface{}, error) {
+		data, err := io.ReadAll(r)
+		decoded = string(data)
+		return map[string]interface{}{}, err
+	}, []string{".Test", ".tst"})
+	doc, err := getConfigDecoder("a/log4g.test")(strings.NewReader("doc"))
+	c.Assert(err, IsNil)
+	c.Assert(doc, DeepEquals, map[string]interface{}{})
+	c.Assert(decoded, Equals, "doc")
+	c.Assert(getConfigDecoder("log4g.TST"), NotNil)
+}
+
+func (s *configDocSuite) TestDocErrors(c *C) {
+	checkDocError(c, `[1]`, "Incorrect config document element /: .*")
+	checkDocError(c, `{"appender": {}}`, "Incorrect config document element /appender: unknown section.*")
+	checkDocError(c, `{"appenders": []}`, "Incorrect config document element /appenders: object is expected")
+	checkDocError(c, `{"appenders": {"a": "b"}}`, "Incorrect config document element /appenders/a: object is expected")
+	checkDocError(c, `{"contexts": {"a.b": {"buffer": {"size": 1}}}}`,
+		"Incorrect config document element /contexts/a.b/buffer: scalar value .*")
+	checkDocError(c, `{"contexts": {"a.b": {"appenders": ["a", ["b"]]}}}`,
+		"Incorrect config document element /contexts/a.b/appenders/1: nested lists .*")
+	checkDocError(c, `{"levels": {"11": {"name": "SEVERE"}}}`, "Incorrect config document element /levels/11: scalar .*")
+}
+
+func checkDocError(c *C, json, expected string) {
+	doc, err := parseJSONDoc(strings.NewReader(json))
+	c.Assert(err, IsNil)
+	_, err = docToProps(doc, nil)
+	c.Assert(err, ErrorMatches, expected)
+}
+
+func (s *configDocSuite) TestSetConfigFile(c *C) {
+	dir := c.MkDir()
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	writeFile(c, filepath.Join(dir, "log4g.json"), `{"appenders": {"ROOT": {"type": "log4g/consoleAppender"}},
+		"contexts": {"": {"appenders": "ROOT"}}, "loggers": {"a": {"level": "DEBUG"}}}`)
+	c.Assert(m.setConfigFile(filepath.Join(dir, "log4g.json")), IsNil)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, DEBUG)
+
+	registerConfigDecoder(parseJSONDoc, []string{".jsn"})
+	writeFile(c, filepath.Join(dir, "log4g.JSN"), `{"appenders": {"ROOT": {"type": "log4g/consoleAppender"}},
+		"contexts": {"": {"appenders": "ROOT"}}, "loggers": {"a": {"level": "TRACE"}}}`)
+	c.Assert(m.setConfigFile(filepath.Join(dir, "log4g.JSN")), IsNil)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, TRACE)
+
+	writeFile(c, filepath.Join(dir, "log4g.properties"), "appender.ROOT.type=log4g/consoleAppender\n"+
+		"context.appenders=ROOT\nlogger.a.level=INFO\n")
+	c.Assert(m.setConfigFile(filepath.Join(dir, "log4g.properties")), IsNil)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, INFO)
+
+	c.Assert(m.setConfigFile(filepath.Join(dir, "unknown.json")), NotNil)
+}
+
+func (s *configDocSuite) TestSemanticErrors(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	err := m.setConfigReader(strings.NewReader(`{"appenders": {"ROOT": {"type": "log4g/consoleAppender"}},
+		"contexts": {"": {"appenders": "ROOT"}}, "loggers": {"a.b": {"level": "LOUD"}}}`), parseJSONDoc)
+	c.Assert(err, ErrorMatches, `Incorrect config document element /loggers/a.b/level: unknown log level "LOUD"`)
+
+	err = m.setConfigReader(strings.NewReader(`{"appenders": {"ROOT": {"type": "log4g/consoleAppender"}},
+		"contexts": {"": {"appenders": ["ROOT", "MISSING"]}}}`), parseJSONDoc)
+	c.Assert(err, ErrorMatches, "Incorrect config document element /contexts//appenders: .*MISSING.*")
+
+	err = m.setConfigReader(strings.NewReader(`{"levels": {"100": "HUGE"},
+		"appenders": {"ROOT": {"type": "log4g/consoleAppender"}}, "contexts": {"": {"appenders": "ROOT"}}}`),
+		parseJSONDoc)
+	c.Assert(err, ErrorMatches, "Incorrect config document element /levels/100: .*")
+
+	// the attribute ignored by the config doesn't hide the error
+	err = m.setConfigReader(strings.NewReader(`{"appenders": {"ROOT": {"type": "log4g/consoleAppender"}},
+		"contexts": {"": {"appenders": "ROOT", "colour": "red"}}, "loggers": {"a": {"level": "NOPE"}}}`),
+		parseJSONDoc)
+	c.Assert(err, ErrorMatches, `Incorrect config document element /contexts//colour: unknown attribute.*\n`+
+		`Incorrect config document element /loggers/a/level: unknown log level "NOPE"`)
+}
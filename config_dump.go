@@ -0,0 +1,118 @@
+package log4g
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// effectiveConfig returns the config properties which build the same config.
+// The overrides are the temporary levels, which are written with their
+// remaining durations
+func (lc *logConfig) effectiveConfig(overrides map[string]*levelOverride) map[string]string {
+	props := make(map[string]string)
+
+	defaultNames := newLogConfig().levelNames
+	for i, name := range lc.levelNames {
+		if name != defaultNames[i] {
+			props[cfgLevel+"."+strconv.Itoa(i)] = name
+		}
+	}
+
+	for name, attrs := range lc.appenderParams {
+		for attr, value := range attrs {
+			props[configKey(cfgAppender, name, attr)] = value
+		}
+	}
+
+	// the context level attribute could be changed by SetLogLevel() or
+	// ResetLogLevel(), the levels are taken from the level settings
+	for name, attrs := range lc.contextParams {
+		for attr, value := range attrs {
+			if attr != cfgContextLevel {
+				props[configKey(cfgContext, name, attr)] = value
+			}
+		}
+	}
+
+	for _, l := range lc.logLevels.Copy() {
+		lls := l.(*logLevelSetting)
+		levelName := lc.configLevelName(lls.level)
+		if levelName == "" {
+			// cannot be referred by the name
+			continue
+		}
+		if lo, ok := overrides[lls.loggerName]; ok {
+			d := time.Until(lo.expires).Round(time.Millisecond)
+			if d < time.Millisecond {
+				d = time.Millisecond
+			}
+			props[configKey(cfgLogger, lls.loggerName, cfgLoggerLevel)] = levelName
+			props[configKey(cfgLogger, lls.loggerName, cfgLoggerLevelDuration)] = d.String()
+			continue
+		}
+		object := cfgLogger
+		if _, ok := lc.contextParams[lls.loggerName]; ok {
+			object = cfgContext
+		}
+		props[configKey(object, lls.loggerName, cfgLoggerLevel)] = levelName
+	}
+
+	// the config always sets the context level, so the context which level
+	// is reset or overridden gets the level it inherits or the override reverts to
+	for name := range lc.contextParams {
+		key := configKey(cfgContext, name, cfgContextLevel)
+		if _, ok := props[key]; ok {
+			continue
+		}
+		level, _ := lc.getLogLevel(name)
+		if lo, ok := overrides[name]; ok {
+			level = lo.prevLevel
+		}
+		if levelName := lc.configLevelName(level); levelName != "" {
+			props[key] = levelName
+		}
+	}
+
+	for _, c := range lc.callers.Copy() {
+		cs := c.(*callerSetting)
+		props[configKey(cfgLogger, cs.loggerName, cfgLoggerCaller)] = strconv.FormatBool(cs.enabled)
+	}
+	return props
+}
+
+// configLevelName returns the name the level can be referred by in the
+// config, or empty string if the level has no name
+func (lc *logConfig) configLevelName(level Level) string {
+	if level < 0 || int(level) >= len(lc.levelNames) {
+		return ""
+	}
+	return lc.levelNames[level]
+}
+
+// loggersInfo returns the settings of the config loggers sorted by names
+func (lc *logConfig) loggersInfo() []LoggerInfo {
+	appNames := make(map[Appender]string, len(lc.appenders))
+	for name, app := range lc.appenders {
+		appNames[app] = name
+	}
+
+	result := make([]LoggerInfo, 0, len(lc.loggers))
+	for _, name := range sortedKeys(lc.loggers) {
+		state := lc.loggers[name].getState()
+		li := LoggerInfo{Name: name, Level: state.logLevel,
+			LevelName: lc.levelName(state.logLevel)}
+		if state.lls != nil {
+			li.LevelSetting = state.lls.loggerName
+		}
+		if state.lctx != nil {
+			li.Context = state.lctx.loggerName
+			for _, app := range state.lctx.targets {
+				li.Appenders = append(li.Appenders, appNames[app])
+			}
+			sort.Strings(li.Appenders)
+		}
+		result = append(result, li)
+	}
+	return result
+}
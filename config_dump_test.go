@@ -0,0 +1,134 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"time"
+)
+
+type configDumpSuite struct {
+}
+
+var _ = Suite(&configDumpSuite{})
+
+func (s *configDumpSuite) TestEffectiveConfig(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.effectiveConfig(), DeepEquals, defaultConfigParams)
+
+	c.Assert(m.setNewProperties(map[string]string{
+		"level.11":              "SEVERE",
+		"appender.ROOT.type":    consoleAppenderName,
+		"appender.AA.type":      consoleAppenderName,
+		"appender.AA.layout":    "%p %m",
+		"context.appenders":     "ROOT",
+		"context.a.b.appenders": "AA",
+		"context.a.b.buffer":    "10",
+		"logger.a.b.c.level":    "DEBUG",
+		"logger.a.b.c.caller":   "true"}), IsNil)
+	m.setLogLevel("a.b", TRACE)
+	m.setLogLevel("x", WARN)
+	c.Assert(m.setLogLevelName(12, "FINER"), Equals, true)
+
+	props := m.effectiveConfig()
+	c.Assert(props, DeepEquals, map[string]string{
+		"level.11":              "SEVERE",
+		"level.12":              "FINER",
+		"appender.ROOT.type":    consoleAppenderName,
+		"appender.AA.type":      consoleAppenderName,
+		"appender.AA.layout":    "%p %m",
+		"context.appenders":     "ROOT",
+		"context.level":         "INFO",
+		"context.a.b.appenders": "AA",
+		"context.a.b.buffer":    "10",
+		"context.a.b.level":     "TRACE",
+		"logger.a.b.c.level":    "DEBUG",
+		"logger.a.b.c.caller":   "true",
+		"logger.x.level":        "WARN"})
+
+	// the properties build the same config
+	c.Assert(m.setNewProperties(props), IsNil)
+	c.Assert(m.effectiveConfig(), DeepEquals, props)
+}
+
+func (s *configDumpSuite) TestLoggers(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.setNewProperties(map[string]string{
+		"appender.ROOT.type":    consoleAppenderName,
+		"appender.AA.type":      consoleAppenderName,
+		"context.appenders":     "ROOT",
+		"context.level":         "WARN",
+		"context.a.b.appenders": "AA",
+		"logger.a.b.c.level":    "DEBUG"}), IsNil)
+	m.getLogger("a.b.c.d")
+	m.getLogger("x")
+
+	c.Assert(m.loggersInfo(), DeepEquals, []LoggerInfo{
+		{Name: "a.b.c", Level: DEBUG, LevelName: "DEBUG", LevelSetting: "a.b.c", Context: "a.b",
+			Appenders: []string{"AA", "ROOT"}},
+		{Name: "a.b.c.d", Level: DEBUG, LevelName: "DEBUG", LevelSetting: "a.b.c", Context: "a.b",
+			Appenders: []string{"AA", "ROOT"}},
+		{Name: "x", Level: WARN, LevelName: "WARN", LevelSetting: "", Context: "", Appenders: []string{"ROOT"}}})
+}
+
+func (s *configDumpSuite) TestUnnamedLevels(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	m.setLogLevel("a", Level(100))
+	m.setLogLevel("b", Level(12))
+	m.getLogger("a")
+	m.getLogger("b")
+	c.Assert(m.loggersInfo(), DeepEquals, []LoggerInfo{
+		{Name: "a", Level: Level(100), LevelName: "100", LevelSetting: "a", Context: "", Appenders: []string{"ROOT"}},
+		{Name: "b", Level: Level(12), LevelName: "12", LevelSetting: "b", Context: "", Appenders: []string{"ROOT"}}})
+	// the levels which cannot be referred by names are not in the config
+	c.Assert(m.effectiveConfig(), DeepEquals, defaultConfigParams)
+}
+
+func (s *configDumpSuite) TestEffectiveConfigRoundTrip(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.setNewProperties(map[string]string{
+		"appender.ROOT.type":  consoleAppenderName,
+		"context.appenders":   "ROOT",
+		"context.a.appenders": "ROOT",
+		"context.a.level":     "DEBUG",
+		"context.x.appenders": "ROOT",
+		"context.x.level":     "WARN",
+		"logger.b.level":      "ERROR"}), IsNil)
+	c.Assert(m.resetLogLevel("a"), Equals, true)
+	c.Assert(m.resetLogLevel("b"), Equals, true)
+	c.Assert(m.setLogLevelFor("c", TRACE, time.Hour), IsNil)
+	c.Assert(m.setLogLevelFor("x", DEBUG, time.Hour), IsNil)
+
+	props := m.effectiveConfig()
+	c.Assert(props["context.a.level"], Equals, "INFO")
+	c.Assert(props["context.x.level"], Equals, "WARN")
+	c.Assert(props["logger.x.level"], Equals, "DEBUG")
+	c.Assert(props["logger.c.level"], Equals, "TRACE")
+	c.Assert(props["logger.c.levelDuration"], Matches, "(59m5.*s|1h0m0s)")
+	_, ok := props["logger.b.level"]
+	c.Assert(ok, Equals, false)
+
+	levels := func() []Level {
+		var result []Level
+		for _, name := range []string{"a", "b", "c", "x"} {
+			l, _ := m.getLogLevel(name)
+			result = append(result, l)
+		}
+		return result
+	}
+	before := levels()
+	c.Assert(m.setNewProperties(props), IsNil)
+	c.Assert(levels(), DeepEquals, before)
+
+	// the overrides revert to the levels before them
+	c.Assert(m.overrides["c"].prevLevel, Equals, INFO)
+	c.Assert(m.overrides["c"].prevExplicit, Equals, false)
+	c.Assert(m.overrides["x"].prevLevel, Equals, WARN)
+}
@@ -0,0 +1,132 @@
+package log4g
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// propsResolver replaces ${name} and ${name:-default} references in the
+// config values. The name refers to another config property, or to an
+// environment variable if there is no property with the name. The default
+// value is used if the name is not defined or its value is empty
+type propsResolver struct {
+	props    map[string]string
+	resolved map[string]string
+	// keys which values are being resolved, to detect cyclic references
+	path []string
+	// keys of the properties referred by other values
+	referred map[string]bool
+}
+
+func newPropsResolver(props map[string]string) *propsResolver {
+	return &propsResolver{props: props, resolved: make(map[string]string, len(props)),
+		referred: make(map[string]bool)}
+}
+
+// substituteProps returns new properties map with resolved references in
+// the values. The props map is not changed.
+func substituteProps(props map[string]string) (map[string]string, error) {
+	resolved, errs := resolveProps(props)
+	// the keys are sorted to report the same error for the same props
+	for _, k := range sortedKeys(errs) {
+		return nil, errs[k]
+	}
+	return resolved, nil
+}
+
+// resolveProps returns the properties which references are resolved, and the
+// errors of the properties which are not resolved by their keys
+func resolveProps(props map[string]string) (map[string]string, map[string]error) {
+	pr := newPropsResolver(props)
+	errs := pr.resolveAll()
+	return pr.resolved, errs
+}
+
+// resolveAll resolves all the properties and returns the errors by the keys
+func (pr *propsResolver) resolveAll() map[string]error {
+	errs := make(map[string]error)
+	for k := range pr.props {
+		if _, err := pr.resolveKey(k); err != nil {
+			errs[k] = err
+		}
+	}
+	return errs
+}
+
+func (pr *propsResolver) resolveKey(key string) (string, error) {
+	if v, ok := pr.resolved[key]; ok {
+		return v, nil
+	}
+	for i, k := range pr.path {
+		if k == key {
+			cycle := append(append([]string{}, pr.path[i:]...), key)
+			return "", errors.New("Cyclic reference in the config: " + strings.Join(cycle, " -> "))
+		}
+	}
+
+	pr.path = append(pr.path, key)
+	v, err := pr.substitute(key, pr.props[key])
+	pr.path = pr.path[:len(pr.path)-1]
+	if err != nil {
+		return "", err
+	}
+	pr.resolved[key] = v
+	return v, nil
+}
+
+func (pr *propsResolver) substitute(key, value string) (string, error) {
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			sb.WriteString(value)
+			return sb.String(), nil
+		}
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			return "", errors.New("Unclosed reference \"" + value[start:] + "\" in the value of " + key)
+		}
+
+		sb.WriteString(value[:start])
+		v, err := pr.lookup(key, value[start+2:start+end])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(v)
+		value = value[start+end+1:]
+	}
+}
+
+// lookup returns the value of the reference found in the value of the key
+func (pr *propsResolver) lookup(key, ref string) (string, error) {
+	name, def, hasDef := ref, "", false
+	if idx := strings.Index(ref, ":-"); idx >= 0 {
+		name, def, hasDef = ref[:idx], ref[idx+2:], true
+	}
+	name = strings.Trim(name, " ")
+	if len(name) == 0 {
+		return "", errors.New("Empty reference ${" + ref + "} in the value of " + key)
+	}
+
+	v, ok := "", false
+	if _, isProp := pr.props[name]; isProp {
+		pr.referred[name] = true
+		var err error
+		if v, err = pr.resolveKey(name); err != nil {
+			return "", err
+		}
+		ok = true
+	} else {
+		v, ok = os.LookupEnv(name)
+	}
+
+	if hasDef && len(v) == 0 {
+		return def, nil
+	}
+	if !ok {
+		return "", errors.New("Undefined reference ${" + ref + "} in the value of " + key +
+			": there is no config property or environment variable " + name)
+	}
+	return v, nil
+}
@@ -0,0 +1,78 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"os"
+)
+
+type configSubstSuite struct {
+}
+
+var _ = Suite(&configSubstSuite{})
+
+func (s *configSubstSuite) SetUpTest(c *C) {
+	os.Setenv("LOG4G_TEST_DIR", "/var/log")
+	os.Setenv("LOG4G_TEST_EMPTY", "")
+}
+
+func (s *configSubstSuite) TearDownTest(c *C) {
+	os.Unsetenv("LOG4G_TEST_DIR")
+	os.Unsetenv("LOG4G_TEST_EMPTY")
+}
+
+func (s *configSubstSuite) TestSubstituteProps(c *C) {
+	props := map[string]string{
+		"dir":                     "${LOG4G_TEST_DIR}/app",
+		"appender.file.fileName":  "${dir}/${name:-app}.log",
+		"appender.file.layout":    "%d{15:04} %m",
+		"context.level":           "${LOG4G_TEST_LEVEL:-INFO}",
+		"context.a.level":         "${LOG4G_TEST_EMPTY:-DEBUG}",
+		"context.b.level":         "${ LOG4G_TEST_EMPTY }",
+		"context.c.level":         "${context.level}${context.level}",
+		"context.c.bufferWithDef": "${LOG4G_TEST_DIR:-}"}
+	res, err := substituteProps(props)
+	c.Assert(err, IsNil)
+	c.Assert(res, DeepEquals, map[string]string{
+		"dir":                     "/var/log/app",
+		"appender.file.fileName":  "/var/log/app/app.log",
+		"appender.file.layout":    "%d{15:04} %m",
+		"context.level":           "INFO",
+		"context.a.level":         "DEBUG",
+		"context.b.level":         "",
+		"context.c.level":         "INFOINFO",
+		"context.c.bufferWithDef": "/var/log"})
+	// the source map is not changed
+	c.Assert(props["dir"], Equals, "${LOG4G_TEST_DIR}/app")
+}
+
+func (s *configSubstSuite) TestSubstitutePropsErrors(c *C) {
+	_, err := substituteProps(map[string]string{"a": "${LOG4G_TEST_UNDEFINED}"})
+	c.Assert(err, ErrorMatches, "Undefined reference \\${LOG4G_TEST_UNDEFINED} in the value of a.*")
+
+	_, err = substituteProps(map[string]string{"a": "${b", "b": "1"})
+	c.Assert(err, ErrorMatches, "Unclosed reference .* in the value of a")
+
+	_, err = substituteProps(map[string]string{"a": "${}"})
+	c.Assert(err, ErrorMatches, "Empty reference .*")
+
+	_, err = substituteProps(map[string]string{"a": "${b}", "b": "x${c}", "c": "${a}"})
+	c.Assert(err, ErrorMatches, "Cyclic reference in the config: a -> b -> c -> a")
+
+	_, err = substituteProps(map[string]string{"a": "${a:-1}"})
+	c.Assert(err, ErrorMatches, "Cyclic reference in the config: a -> a")
+}
+
+func (s *configSubstSuite) TestConfig(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	c.Assert(m.setNewProperties(map[string]string{
+		"appender.ROOT.type": consoleAppenderName,
+		"context.appenders":  "ROOT",
+		"logger.a.level":     "${LOG4G_TEST_LEVEL:-DEBUG}"}), IsNil)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, DEBUG)
+
+	config := m.config
+	c.Assert(m.setNewProperties(map[string]string{"logger.a.level": "${LOG4G_TEST_LEVEL}"}), NotNil)
+	c.Assert(m.config, Equals, config)
+}
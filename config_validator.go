@@ -0,0 +1,327 @@
+package log4g
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appenderReferrer is implemented by factories which appenders refer to
+// other appenders by names, it lets Validate() check the references
+type appenderReferrer interface {
+	// returns the names of the referred appenders by the param names
+	referredAppenders(params map[string]string) map[string][]string
+}
+
+// configValidator collects all the problems of the config properties
+type configValidator struct {
+	// keeps level names and appender factories the properties are checked with
+	lc   *logConfig
+	locs map[string]propLocation
+	errs []ConfigError
+	// the keys which values cannot be resolved, they are reported once
+	unresolved map[string]error
+}
+
+func (lm *logManager) validate(props map[string]string, locs map[string]propLocation) []ConfigError {
+	lm.rwLock.RLock()
+	cv := newConfigValidator(lm.config, locs)
+	lm.rwLock.RUnlock()
+
+	return cv.validate(props)
+}
+
+func (lm *logManager) validateFile(configFileName string) []ConfigError {
+	var props map[string]string
+	var locs map[string]propLocation
+	var err error
+	if decoder := getConfigDecoder(configFileName); decoder != nil {
+		props, err = loadConfigDoc(configFileName, decoder)
+	} else {
+		locs = make(map[string]propLocation)
+		props, err = loadPropsFile(configFileName, locs)
+	}
+	if err != nil {
+		return []ConfigError{{File: configFileName, Msg: err.Error()}}
+	}
+
+	errs := lm.validate(props, locs)
+	for i := range errs {
+		if errs[i].File == "" {
+			errs[i].File = configFileName
+		}
+	}
+	return errs
+}
+
+func loadConfigDoc(configFileName string, decoder ConfigDecoder) (map[string]string, error) {
+	f, err := os.Open(configFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := decoder(f)
+	if err != nil {
+		return nil, err
+	}
+	return docToProps(doc, nil)
+}
+
+func newConfigValidator(config *logConfig, locs map[string]propLocation) *configValidator {
+	lc := newLogConfig()
+	copy(lc.levelNames, config.levelNames)
+	for k, v := range config.appenderFactorys {
+		lc.appenderFactorys[k] = v
+	}
+	return &configValidator{lc: lc, locs: locs}
+}
+
+func (cv *configValidator) validate(props map[string]string) []ConfigError {
+	pr := newPropsResolver(props)
+	errs := pr.resolveAll()
+	for k, err := range errs {
+		cv.addError(k, err.Error())
+	}
+	cv.unresolved = errs
+	resolved := pr.resolved
+
+	levels := make(map[string]string)
+	apps := make(map[string]map[string]string)
+	ctxs := make(map[string]map[string]string)
+	loggers := make(map[string]map[string]string)
+	for _, key := range sortedKeys(resolved) {
+		value := resolved[key]
+		object := key
+		if idx := strings.Index(key, "."); idx >= 0 {
+			object = key[:idx]
+		}
+
+		switch object {
+		case cfgLevel:
+			cv.checkLevel(key, value, levels)
+		case cfgAppender:
+			cv.group(key, value, cfgAppender, isCorrectAppenderName, apps)
+		case cfgContext:
+			cv.group(key, value, cfgContext, isCorrectLoggerName, ctxs)
+		case cfgLogger:
+			cv.group(key, value, cfgLogger, isCorrectLoggerName, loggers)
+		default:
+			// the property can be defined to be referred by other values only
+			if pr.referred[key] {
+				continue
+			}
+			cv.addError(key, "unknown key, expected "+cfgLevel+", "+cfgAppender+", "+cfgContext+" or "+
+				cfgLogger+" property")
+		}
+	}
+	cv.lc.applyLevelParams(levels)
+
+	cv.checkAppenders(apps)
+	cv.checkContexts(ctxs, apps)
+	cv.checkLoggers(loggers)
+
+	sort.SliceStable(cv.errs, func(i, j int) bool { return cv.errs[i].Key < cv.errs[j].Key })
+	return cv.errs
+}
+
+func (cv *configValidator) addError(key, msg string) {
+	if _, ok := cv.unresolved[key]; ok {
+		return
+	}
+	ce := ConfigError{Key: key, Msg: msg}
+	if loc, ok := cv.locs[key]; ok {
+		ce.File, ce.Line = loc.fileName, loc.lineNum
+	}
+	cv.errs = append(cv.errs, ce)
+}
+
+// checks level.X=<levelName> property and puts it to levels if it is correct
+func (cv *configValidator) checkLevel(key, value string, levels map[string]string) {
+	level, err := strconv.Atoi(strings.TrimPrefix(key, cfgLevel+"."))
+	if err != nil || level < 0 || level > int(ALL) {
+		cv.addError(key, "incorrect log level id, expected "+cfgLevel+".<id> where the id is in [0.."+
+			strconv.Itoa(int(ALL))+"]")
+		return
+	}
+	levels[key] = value
+}
+
+// puts the property in <prefix>.<name>.<attribute> form to the groups
+func (cv *configValidator) group(key, value, prefix string, checker func(string) bool,
+	groups map[string]map[string]string) {
+	nameAttr := strings.TrimPrefix(key, prefix+".")
+	if nameAttr == key {
+		cv.addError(key, "expected "+prefix+".<name>.<attribute> key")
+		return
+	}
+
+	name, attr := "", nameAttr
+	if idx := strings.LastIndex(nameAttr, "."); idx >= 0 {
+		name, attr = nameAttr[:idx], nameAttr[idx+1:]
+	}
+	if attr == "" {
+		cv.addError(key, "attribute is not specified")
+		return
+	}
+	if !checker(name) {
+		cv.addError(key, "incorrect "+prefix+" name \""+name+"\"")
+		return
+	}
+
+	g, ok := groups[name]
+	if !ok {
+		g = make(map[string]string)
+		groups[name] = g
+	}
+	g[attr] = value
+}
+
+func (cv *configValidator) checkAppenders(apps map[string]map[string]string) {
+	for _, name := range sortedKeys(apps) {
+		attrs := apps[name]
+		typeKey := configKey(cfgAppender, name, cfgAppenderType)
+		t := strings.Trim(attrs[cfgAppenderType], " ")
+		if t == "" {
+			cv.addError(typeKey, "appender type is not specified")
+			continue
+		}
+		f, ok := cv.lc.appenderFactorys[t]
+		if !ok {
+			cv.addError(typeKey, "no appender factory is registered for the appender type \""+t+"\"")
+			continue
+		}
+
+		if pc, ok := f.(ParamsChecker); ok {
+			cv.checkUnknownAttributes(cfgAppender, name, attrs, append(pc.Params(), cfgAppenderType))
+			errs := pc.CheckParams(attrs)
+			for _, param := range sortedKeys(errs) {
+				key := typeKey
+				if param != "" {
+					key = configKey(cfgAppender, name, param)
+				}
+				cv.addError(key, errs[param].Error())
+			}
+		}
+
+		if ar, ok := f.(appenderReferrer); ok {
+			refs := ar.referredAppenders(attrs)
+			for _, param := range sortedKeys(refs) {
+				for _, ref := range refs[param] {
+					if _, ok := apps[ref]; !ok {
+						cv.addError(configKey(cfgAppender, name, param), "undefined appender name \""+ref+"\"")
+					}
+				}
+			}
+		}
+	}
+}
+
+func (cv *configValidator) checkContexts(ctxs, apps map[string]map[string]string) {
+	for _, name := range sortedKeys(ctxs) {
+		attrs := ctxs[name]
+		cv.checkUnknownAttributes(cfgContext, name, attrs, contextAttributes)
+
+		appsKey := configKey(cfgContext, name, cfgContextAppenders)
+		appNames := strings.Trim(attrs[cfgContextAppenders], " ")
+		if appNames == "" {
+			cv.addError(appsKey, "context should refer to at least one appender")
+		} else {
+			for _, app := range strings.Split(appNames, ",") {
+				app = strings.Trim(app, " ")
+				if _, ok := apps[app]; !ok {
+					cv.addError(appsKey, "undefined appender name \""+app+"\"")
+				}
+			}
+		}
+
+		cv.checkLevelName(cfgContext, name, cfgContextLevel, attrs)
+		if v, ok := attrs[cfgContextBufSize]; ok {
+			if _, err := ParseInt64(v, 1, maxContextBufSize, 100); err != nil {
+				cv.addError(configKey(cfgContext, name, cfgContextBufSize), "incorrect buffer size: "+err.Error())
+			}
+		}
+		cv.checkBool(cfgContext, name, cfgContextInherited, attrs)
+		cv.checkBool(cfgContext, name, cfgContextBlocking, attrs)
+		cv.checkBool(cfgContext, name, cfgContextCaller, attrs)
+		if v, ok := attrs[cfgContextOverflow]; ok {
+			if msg := panicMessage(func() { cv.lc.getOverflowPolicy(v, name) }); msg != "" {
+				cv.addError(configKey(cfgContext, name, cfgContextOverflow), msg)
+			}
+		}
+	}
+}
+
+func (cv *configValidator) checkLoggers(loggers map[string]map[string]string) {
+	for _, name := range sortedKeys(loggers) {
+		attrs := loggers[name]
+		cv.checkUnknownAttributes(cfgLogger, name, attrs, loggerAttributes)
+		cv.checkLevelName(cfgLogger, name, cfgLoggerLevel, attrs)
+		cv.checkBool(cfgLogger, name, cfgLoggerCaller, attrs)
+		if v, ok := attrs[cfgLoggerLevelDuration]; ok {
+			key := configKey(cfgLogger, name, cfgLoggerLevelDuration)
+			if _, ok := attrs[cfgLoggerLevel]; !ok {
+				cv.addError(key, "the attribute should be specified with "+cfgLoggerLevel+" attribute")
+			} else if d, err := time.ParseDuration(strings.Trim(v, " ")); err != nil || d <= 0 {
+				cv.addError(key, "incorrect value \""+v+"\", expected positive duration like 15m")
+			}
+		}
+	}
+}
+
+func (cv *configValidator) checkUnknownAttributes(object, name string, attrs map[string]string, known []string) {
+	for _, attr := range sortedKeys(attrs) {
+		if !containsString(known, attr) {
+			cv.addError(configKey(object, name, attr), "unknown attribute \""+attr+"\", expected one of "+
+				strings.Join(known, ", "))
+		}
+	}
+}
+
+func (cv *configValidator) checkLevelName(object, name, attr string, attrs map[string]string) {
+	levelName, ok := attrs[attr]
+	if ok && strings.Trim(levelName, " ") != "" && cv.lc.getLevelByName(levelName) < 0 {
+		cv.addError(configKey(object, name, attr), "unknown log level \""+levelName+"\"")
+	}
+}
+
+func (cv *configValidator) checkBool(object, name, attr string, attrs map[string]string) {
+	if v, ok := attrs[attr]; ok {
+		if _, err := ParseBool(v, false); err != nil {
+			cv.addError(configKey(object, name, attr), "incorrect value \""+v+"\", should be true or false")
+		}
+	}
+}
+
+// configKey returns the property key for the object attribute, the name is
+// omitted for the root logger name
+func configKey(object, name, attr string) string {
+	if name == rootLoggerName {
+		return object + "." + attr
+	}
+	return object + "." + name + "." + attr
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// panicMessage calls f and returns the message of the panic, or "" if f
+// doesn't panic
+func panicMessage(f func()) (msg string) {
+	defer func() {
+		if p := recover(); p != nil {
+			msg = fmt.Sprint(p)
+		}
+	}()
+	f()
+	return ""
+}
@@ -0,0 +1,114 @@
+package log4g
+
+import (
+	. "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
+)
+
+type configValidatorSuite struct {
+}
+
+var _ = Suite(&configValidatorSuite{})
+
+func errorKeys(errs []ConfigError) []string {
+	keys := make([]string, len(errs))
+	for i, ce := range errs {
+		keys[i] = ce.Key
+	}
+	return keys
+}
+
+func (s *configValidatorSuite) TestCorrectConfig(c *C) {
+	c.Assert(Validate(map[string]string{
+		"dir":                       "/var/log",
+		"level.11":                  "SEVERE",
+		"appender.console.type":     consoleAppenderName,
+		"appender.console.layout":   "%p %m",
+		"appender.file.type":        fileAppenderName,
+		"appender.file.fileName":    "${dir}/app.log",
+		"appender.file.maxFileSize": "20M",
+		"appender.file.layout":      "%d{15:04} %p %m",
+		"appender.fo.type":          failoverAppenderName,
+		"appender.fo.primary":       "file",
+		"appender.fo.secondaries":   "console",
+		"context.appenders":         "console",
+		"context.buffer":            "100",
+		"context.level":             "SEVERE",
+		"context.a.b.appenders":     "fo, console",
+		"context.a.b.overflow":      "dropOldest",
+		"context.a.b.blocking":      "false",
+		"logger.a.b.c.level":        "trace",
+		"logger.a.b.c.caller":       "true"}), IsNil)
+}
+
+func (s *configValidatorSuite) TestAllErrors(c *C) {
+	errs := Validate(map[string]string{
+		"unused":                    "value",
+		"level.100":                 "HUGE",
+		"appender.console.type":     consoleAppenderName,
+		"appender.console.layout":   "%m",
+		"appender.file.type":        fileAppenderName,
+		"appender.file.layout":      "%m",
+		"appender.file.fileName":    "____test____validate",
+		"appender.file.maxFileSzie": "20M",
+		"appender.file.rotate":      "weekly",
+		"appender.unknown.type":     "test/unknown",
+		"appender.fo.type":          failoverAppenderName,
+		"appender.fo.primary":       "file",
+		"appender.fo.secondaries":   "nowhere",
+		"context.appenders":         "console,missing",
+		"context.buffer":            "0",
+		"context.level":             "LOUD",
+		"context.a.b.blocking":      "maybe",
+		"context.a.b.bufer":         "10",
+		"context.a.b.appenders":     "${UNDEFINED_LOG4G_TEST_VAR}",
+		"logger.a.b.c.level":        "QUIET",
+		"logger.a..level":           "INFO"})
+	c.Assert(errorKeys(errs), DeepEquals, []string{
+		"appender.file.maxFileSzie",
+		"appender.file.rotate",
+		"appender.fo.secondaries",
+		"appender.unknown.type",
+		"context.a.b.appenders",
+		"context.a.b.blocking",
+		"context.a.b.bufer",
+		"context.appenders",
+		"context.buffer",
+		"context.level",
+		"level.100",
+		"logger.a..level",
+		"logger.a.b.c.level",
+		"unused"})
+
+	_, err := os.Stat("____test____validate")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *configValidatorSuite) TestValidateFile(c *C) {
+	dir := c.MkDir()
+	writeFile(c, filepath.Join(dir, "base.properties"), "appender.console.type=log4g/consoleAppender\n"+
+		"appender.console.layot=%p %m\nappender.console.layout=%p %m\n")
+	writeFile(c, filepath.Join(dir, "log4g.properties"), "# test config\n"+
+		"include=base.properties\n"+
+		"\n"+
+		"context.appenders=console\n"+
+		"context.level=LOUD\n")
+
+	errs := ValidateFile(filepath.Join(dir, "log4g.properties"))
+	c.Assert(errorKeys(errs), DeepEquals, []string{"appender.console.layot", "context.level"})
+	c.Assert(errs[0].File, Equals, filepath.Join(dir, "base.properties"))
+	c.Assert(errs[0].Line, Equals, 2)
+	c.Assert(errs[1].File, Equals, filepath.Join(dir, "log4g.properties"))
+	c.Assert(errs[1].Line, Equals, 5)
+	c.Assert(errs[1].Error(), Matches, ".*log4g.properties:5: context.level: unknown log level \"LOUD\"")
+
+	writeFile(c, filepath.Join(dir, "log4g.json"), `{"contexts": {"": {"appenders": ["console"], "level": "INFO"}}}`)
+	errs = ValidateFile(filepath.Join(dir, "log4g.json"))
+	c.Assert(errorKeys(errs), DeepEquals, []string{"context.appenders"})
+	c.Assert(errs[0].File, Equals, filepath.Join(dir, "log4g.json"))
+
+	errs = ValidateFile(filepath.Join(dir, "absent.properties"))
+	c.Assert(len(errs), Equals, 1)
+	c.Assert(errs[0].Key, Equals, "")
+}
@@ -0,0 +1,189 @@
+package log4g
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// configWatcher checks the config file periodically and re-applies it when
+// the modification time and the content of the file, or of a file included by
+// it, are changed
+type configWatcher struct {
+	lm       *logManager
+	fileName string
+	onError  func(err error)
+	// the files of the applied config with their modification times, and
+	// the hash of their contents
+	files []watchedFile
+	hash  [sha256.Size]byte
+	// the content hash of the file which failed to apply. It is applied again
+	// on every check, but its error is reported once
+	failedHash [sha256.Size]byte
+	stopCh     chan bool
+	// closed when run() exits
+	doneCh chan bool
+}
+
+// watchedFile is the config file or a file included by it. The modification
+// time is zero if the included file doesn't exist
+type watchedFile struct {
+	name    string
+	modTime time.Time
+}
+
+func newConfigWatcher(lm *logManager, configFileName string, onError func(err error)) *configWatcher {
+	cw := &configWatcher{lm: lm, fileName: configFileName, onError: onError, stopCh: make(chan bool),
+		doneCh: make(chan bool)}
+	if files, hash, changed, err := cw.changed(); err == nil && changed {
+		cw.files, cw.hash = files, hash
+	}
+	return cw
+}
+
+func (cw *configWatcher) run(interval time.Duration) {
+	defer close(cw.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cw.check()
+		case <-cw.stopCh:
+			return
+		}
+	}
+}
+
+// check re-applies the config file if it is changed. The current config is
+// kept if the file cannot be read or it contains an incorrect config, the
+// file is applied again on the next check then
+func (cw *configWatcher) check() {
+	files, hash, changed, err := cw.changed()
+	if err == nil && changed {
+		if err = cw.lm.setConfigFile(cw.fileName); err == nil {
+			cw.files, cw.hash = files, hash
+			return
+		}
+		if hash == cw.failedHash {
+			// reported already
+			return
+		}
+		cw.failedHash = hash
+	}
+	if err == nil {
+		return
+	}
+
+	if cw.onError != nil {
+		cw.onError(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "log4g cannot reload config file %s: %s\n", cw.fileName, err)
+	}
+}
+
+// changed returns whether the config files are changed since they were
+// applied, and the files with their modification times and contents hash. The
+// contents are compared only if a file modification time is changed
+func (cw *configWatcher) changed() ([]watchedFile, [sha256.Size]byte, bool, error) {
+	var hash [sha256.Size]byte
+	if _, err := os.Stat(cw.fileName); err != nil {
+		return nil, hash, false, err
+	}
+	if !cw.modified() {
+		return cw.files, cw.hash, false, nil
+	}
+
+	files, hash, err := readConfigFiles(cw.fileName)
+	if err != nil {
+		return nil, hash, false, err
+	}
+	if hash == cw.hash {
+		// the same content, nothing to apply
+		cw.files = files
+		return files, hash, false, nil
+	}
+	return files, hash, true, nil
+}
+
+// modified returns whether a modification time of the applied files is changed
+func (cw *configWatcher) modified() bool {
+	if len(cw.files) == 0 {
+		return true
+	}
+	for _, f := range cw.files {
+		var modTime time.Time
+		if fInfo, err := os.Stat(f.name); err == nil {
+			modTime = fInfo.ModTime()
+		}
+		if !modTime.Equal(f.modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfigFiles returns the config file and the files included by it if
+// it is a properties file, and the hash of their contents. The included files
+// which cannot be read are hashed as empty ones, the config cannot be applied
+// until they are available
+func readConfigFiles(fileName string) ([]watchedFile, [sha256.Size]byte, error) {
+	h := sha256.New()
+	var files []watchedFile
+	var read func(name string, including []string) error
+	read = func(name string, including []string) error {
+		absName, _ := filepath.Abs(name)
+		for _, n := range including {
+			if n == absName {
+				// cyclic include is reported when the config is applied
+				return nil
+			}
+		}
+
+		var modTime time.Time
+		fInfo, err := os.Stat(name)
+		if err == nil {
+			modTime = fInfo.ModTime()
+		}
+		data, err := os.ReadFile(name)
+		if err != nil && len(including) == 0 {
+			return err
+		}
+		files = append(files, watchedFile{name, modTime})
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+
+		if getConfigDecoder(fileName) != nil {
+			return nil
+		}
+		including = append(including, absName)
+		// the errors are reported when the config is applied
+		readProps(bytes.NewReader(data), name, func(key, value string, lineNum int) error {
+			if key != cfgInclude {
+				return nil
+			}
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(filepath.Dir(name), value)
+			}
+			return read(value, including)
+		})
+		return nil
+	}
+
+	var hash [sha256.Size]byte
+	if err := read(fileName, nil); err != nil {
+		return nil, hash, err
+	}
+	copy(hash[:], h.Sum(nil))
+	return files, hash, nil
+}
+
+// stop stops the watcher and waits until the check in progress is done. It
+// must not be called under the logManager lock, which the check can wait for
+func (cw *configWatcher) stop() {
+	close(cw.stopCh)
+	<-cw.doneCh
+}
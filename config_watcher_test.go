@@ -0,0 +1,164 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+type configWatcherSuite struct {
+}
+
+var _ = Suite(&configWatcherSuite{})
+
+const watchedConfigFile = "____test____config___file"
+
+// writes the config file and moves its modification time forward, so the
+// change is visible even on file systems with coarse time resolution
+func writeConfigFile(c *C, content string, age time.Duration) {
+	c.Assert(os.WriteFile(watchedConfigFile, []byte(content), 0660), IsNil)
+	t := time.Now().Add(age)
+	c.Assert(os.Chtimes(watchedConfigFile, t, t), IsNil)
+}
+
+func (s *configWatcherSuite) TestCheck(c *C) {
+	defer os.Remove(watchedConfigFile)
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n", -time.Hour)
+
+	var errs []error
+	cw := newConfigWatcher(m, watchedConfigFile, func(err error) { errs = append(errs, err) })
+	_, _, changed, err := cw.changed()
+	c.Assert(changed, Equals, false)
+	c.Assert(err, IsNil)
+
+	// same content with new modification time
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n", -time.Minute)
+	_, _, changed, _ = cw.changed()
+	c.Assert(changed, Equals, false)
+
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\nlogger.a.level=DEBUG\n", 0)
+	cw.check()
+	c.Assert(len(errs), Equals, 0)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, DEBUG)
+
+	// incorrect config is reported, the current one is kept
+	config := m.config
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=UNKNOWN\n", time.Minute)
+	cw.check()
+	c.Assert(len(errs), Equals, 1)
+	c.Assert(m.config, Equals, config)
+	// the error is reported once
+	cw.check()
+	c.Assert(len(errs), Equals, 1)
+
+	os.Remove(watchedConfigFile)
+	cw.check()
+	c.Assert(len(errs), Equals, 2)
+}
+
+func (s *configWatcherSuite) TestRetryFailedConfig(c *C) {
+	defer os.Remove(watchedConfigFile)
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n", -time.Hour)
+	cw := newConfigWatcher(m, watchedConfigFile, func(err error) {})
+
+	// the file is caught in the middle of writing, and completed within the
+	// same modification time
+	modTime := time.Now()
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=RO", 0)
+	c.Assert(os.Chtimes(watchedConfigFile, modTime, modTime), IsNil)
+	cw.check()
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\nlogger.a.level=DEBUG\n", 0)
+	c.Assert(os.Chtimes(watchedConfigFile, modTime, modTime), IsNil)
+	cw.check()
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, DEBUG)
+}
+
+func (s *configWatcherSuite) TestIncludedFiles(c *C) {
+	dir := c.MkDir()
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	write := func(name, content string, age time.Duration) {
+		writeFile(c, filepath.Join(dir, name), content)
+		t := time.Now().Add(age)
+		c.Assert(os.Chtimes(filepath.Join(dir, name), t, t), IsNil)
+	}
+	write("log4g.properties", "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n"+
+		"include=levels.properties\n", -time.Hour)
+	write("levels.properties", "logger.a.level=DEBUG\n", -time.Hour)
+
+	var errs []error
+	cw := newConfigWatcher(m, filepath.Join(dir, "log4g.properties"), func(err error) { errs = append(errs, err) })
+	c.Assert(cw.files, HasLen, 2)
+	_, _, changed, err := cw.changed()
+	c.Assert(changed, Equals, false)
+	c.Assert(err, IsNil)
+
+	write("levels.properties", "logger.a.level=TRACE\n", 0)
+	cw.check()
+	c.Assert(errs, HasLen, 0)
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, TRACE)
+
+	// the included file which doesn't exist is applied when it is created
+	write("log4g.properties", "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n"+
+		"include=levels.properties\ninclude=sub/more.properties\n", time.Minute)
+	cw.check()
+	c.Assert(errs, HasLen, 1)
+	c.Assert(os.Mkdir(filepath.Join(dir, "sub"), 0770), IsNil)
+	write("sub/more.properties", "logger.b.level=WARN\n", time.Minute)
+	cw.check()
+	c.Assert(errs, HasLen, 1)
+	c.Assert(m.getLogger("b").(*logger).getState().logLevel, Equals, WARN)
+	c.Assert(cw.files, HasLen, 3)
+	c.Assert(cw.files[2].name, Equals, filepath.Join(dir, "sub", "more.properties"))
+}
+
+func (s *configWatcherSuite) TestRetryDoesNotLeak(c *C) {
+	defer os.Remove(watchedConfigFile)
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n", -time.Hour)
+	c.Assert(m.watchConfigF(watchedConfigFile, time.Millisecond, nil), IsNil)
+
+	// the contexts of the failed config are shut down on every retry
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\ncontext.a.appenders=ROOT\n"+
+		"logger.a.level=NOPE\n", 0)
+	time.Sleep(20 * time.Millisecond)
+	goroutines := runtime.NumGoroutine()
+	time.Sleep(200 * time.Millisecond)
+	c.Assert(runtime.NumGoroutine() <= goroutines+5, Equals, true,
+		Commentf("%d goroutines, %d expected", runtime.NumGoroutine(), goroutines))
+}
+
+func (s *configWatcherSuite) TestWatchConfigF(c *C) {
+	defer os.Remove(watchedConfigFile)
+	m := newTestLogManager(c)
+	c.Assert(m.watchConfigF(watchedConfigFile, time.Millisecond, nil), NotNil)
+
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\n", -time.Hour)
+	c.Assert(m.watchConfigF(watchedConfigFile, 0, nil), NotNil)
+	c.Assert(m.watchConfigF(watchedConfigFile, time.Millisecond, nil), IsNil)
+	c.Assert(m.watcher, NotNil)
+
+	writeConfigFile(c, "appender.ROOT.type="+consoleAppenderName+"\ncontext.appenders=ROOT\nlogger.a.level=TRACE\n", 0)
+	for i := 0; i < 100 && m.getLogger("a").(*logger).getState().logLevel != TRACE; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(m.getLogger("a").(*logger).getState().logLevel, Equals, TRACE)
+
+	cw := m.watcher
+	m.shutdown(context.Background())
+	c.Assert(m.watcher, IsNil)
+	// the watcher cannot apply the config after the shutdown
+	select {
+	case <-cw.doneCh:
+	default:
+		c.Fatal("the watcher is running after shutdown")
+	}
+}
@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -20,12 +21,15 @@ type consoleAppender struct {
 type consoleAppenderFactory struct {
 	msgChannel chan string
 	out        io.Writer
+	flushCh    chan chan bool
+	// closed when the writing go routine is over
+	doneCh chan bool
 }
 
 var caFactory *consoleAppenderFactory
 
 func init() {
-	caFactory = &consoleAppenderFactory{make(chan string, 1000), os.Stdout}
+	caFactory = &consoleAppenderFactory{make(chan string, 1000), os.Stdout, make(chan chan bool), make(chan bool)}
 
 	err := RegisterAppender(caFactory)
 	if err != nil {
@@ -34,36 +38,77 @@ func init() {
 		panic(err)
 	}
 	go func() {
+		defer close(caFactory.doneCh)
 		for {
-			str, ok := <-caFactory.msgChannel
-			if !ok {
-				break
+			select {
+			case str, ok := <-caFactory.msgChannel:
+				if !ok {
+					return
+				}
+				fmt.Fprint(caFactory.out, str, "\n")
+			case req := <-caFactory.flushCh:
+				caFactory.drain()
+				close(req)
 			}
-			fmt.Fprint(caFactory.out, str, "\n")
 		}
 	}()
 }
 
+// writes messages which are in the channel buffer at the moment
+func (caf *consoleAppenderFactory) drain() {
+	for {
+		select {
+		case str, ok := <-caf.msgChannel:
+			if !ok {
+				return
+			}
+			fmt.Fprint(caf.out, str, "\n")
+		default:
+			return
+		}
+	}
+}
+
 func (*consoleAppenderFactory) Name() string {
 	return consoleAppenderName
 }
 
 func (caf *consoleAppenderFactory) NewAppender(params map[string]string) (Appender, error) {
-	layout, ok := params[CAParamLayout]
-	if !ok || len(layout) == 0 {
-		return nil, errors.New("Cannot create console appender without specified layout")
+	layoutTemplate, err := parseConsoleLayout(params)
+	if err != nil {
+		return nil, err
+	}
+	return &consoleAppender{layoutTemplate}, nil
+}
+
+// ParamsChecker implementation
+func (caf *consoleAppenderFactory) Params() []string {
+	return append([]string{CAParamLayout}, layoutParams...)
+}
+
+// ParamsChecker implementation
+func (caf *consoleAppenderFactory) CheckParams(params map[string]string) map[string]error {
+	if _, err := parseConsoleLayout(params); err != nil {
+		return map[string]error{CAParamLayout: err}
 	}
+	return nil
+}
 
-	layoutTemplate, err := ParseLayout(layout)
+func parseConsoleLayout(params map[string]string) (LayoutTemplate, error) {
+	layoutTemplate, err := parseLayoutParams(params[CAParamLayout], params)
+	if err == errNoLayout {
+		return nil, errors.New("Cannot create console appender without specified layout")
+	}
 	if err != nil {
 		return nil, errors.New("Cannot create console appender: " + err.Error())
 	}
-
-	return &consoleAppender{layoutTemplate}, nil
+	return layoutTemplate, nil
 }
 
+// Shutdown waits until all messages are printed
 func (caf *consoleAppenderFactory) Shutdown() {
 	close(caf.msgChannel)
+	<-caf.doneCh
 }
 
 // Appender interface implementation
@@ -76,6 +121,12 @@ func (cAppender *consoleAppender) Append(event *LogEvent) (ok bool) {
 	return ok
 }
 
+// Flusher implementation. All console appenders share one channel, so the
+// call waits for messages of all of them
+func (cAppender *consoleAppender) Flush(ctx context.Context) error {
+	return requestFlush(ctx, caFactory.flushCh, caFactory.doneCh)
+}
+
 func (cAppender *consoleAppender) Shutdown() {
 	// Nothing should be done for the console appender
 }
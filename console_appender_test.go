@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	. "gopkg.in/check.v1"
 	"time"
 )
@@ -37,12 +38,13 @@ func (s *cAppenderSuite) TestAppend(c *C) {
 	caFactory.out = s
 
 	a, _ := caFactory.NewAppender(map[string]string{"layout": "[%d{15:04:05.000}] %p %c: %m"})
-	appended := a.Append(&LogEvent{FATAL, time.Unix(123456, 0), "a.b.c", "Hello Console!"})
+	appended := a.Append(&LogEvent{Level: FATAL, Timestamp: time.Unix(123456, 0), LoggerName: "a.b.c", Payload: "Hello Console!"})
 	c.Assert(appended, Equals, true)
 	<-s.signal
 	c.Assert(s.msg, Equals, "[02:17:36.000] FATAL a.b.c: Hello Console!\n")
 
 	caFactory.Shutdown()
-	appended = a.Append(&LogEvent{FATAL, time.Unix(0, 0), "a.b.c", "Never delivered"})
+	appended = a.Append(&LogEvent{Level: FATAL, Timestamp: time.Unix(0, 0), LoggerName: "a.b.c", Payload: "Never delivered"})
 	c.Assert(appended, Equals, false)
+	c.Assert(a.(Flusher).Flush(context.Background()), IsNil)
 }
@@ -0,0 +1,62 @@
+package log4g
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// errorHandlers is an immutable set of the registered error handlers. It is
+// replaced as a whole when a handler is changed, so appenders can read it
+// without any locks
+type errorHandlers struct {
+	global    ErrorHandler
+	appenders map[string]ErrorHandler
+}
+
+var (
+	errHandlers     atomic.Pointer[errorHandlers]
+	errHandlersLock sync.Mutex
+)
+
+// setErrorHandler sets the handler for the appender name, or the global one
+// if the name is empty
+func setErrorHandler(appenderName string, handler ErrorHandler) {
+	errHandlersLock.Lock()
+	defer errHandlersLock.Unlock()
+
+	ehs := &errorHandlers{appenders: make(map[string]ErrorHandler)}
+	if old := errHandlers.Load(); old != nil {
+		ehs.global = old.global
+		for name, h := range old.appenders {
+			ehs.appenders[name] = h
+		}
+	}
+
+	switch {
+	case appenderName == "":
+		ehs.global = handler
+	case handler == nil:
+		delete(ehs.appenders, appenderName)
+	default:
+		ehs.appenders[appenderName] = handler
+	}
+	errHandlers.Store(ehs)
+}
+
+// reportError passes the error to the handler registered for the appender
+// name or to the global one. It returns false if there is no handler
+func reportError(appenderName string, event *LogEvent, err error) bool {
+	ehs := errHandlers.Load()
+	if ehs == nil {
+		return false
+	}
+	h, ok := ehs.appenders[appenderName]
+	if !ok {
+		h = ehs.global
+	}
+	if h == nil {
+		return false
+	}
+	h(event, err)
+	return true
+}
@@ -0,0 +1,84 @@
+package log4g
+
+import (
+	"context"
+	"errors"
+	. "gopkg.in/check.v1"
+	"time"
+)
+
+type errorHandlerSuite struct {
+}
+
+var _ = Suite(&errorHandlerSuite{})
+
+func (s *errorHandlerSuite) TearDownTest(c *C) {
+	errHandlers.Store(nil)
+}
+
+func (s *errorHandlerSuite) TestReportError(c *C) {
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+	err := errors.New("test")
+	c.Assert(reportError("a", le, err), Equals, false)
+
+	var global, own []string
+	SetErrorHandler(func(event *LogEvent, err error) { global = append(global, err.Error()) })
+	SetAppenderErrorHandler("a", func(event *LogEvent, err error) {
+		c.Assert(event, Equals, le)
+		own = append(own, err.Error())
+	})
+	c.Assert(reportError("a", le, err), Equals, true)
+	c.Assert(reportError("b", le, err), Equals, true)
+	c.Assert(own, DeepEquals, []string{"test"})
+	c.Assert(global, DeepEquals, []string{"test"})
+
+	SetAppenderErrorHandler("a", nil)
+	c.Assert(reportError("a", le, err), Equals, true)
+	c.Assert(global, DeepEquals, []string{"test", "test"})
+
+	SetErrorHandler(nil)
+	c.Assert(reportError("a", le, err), Equals, false)
+}
+
+func (s *errorHandlerSuite) TestStatAppenderErrors(c *C) {
+	var events []*LogEvent
+	SetAppenderErrorHandler("fail", func(event *LogEvent, err error) {
+		c.Assert(err.Error(), Equals, "the appender could not append the event")
+		events = append(events, event)
+	})
+	sa := newStatAppender("fail", &failingAppender{})
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+	c.Assert(sa.Append(le), Equals, false)
+	c.Assert(events, DeepEquals, []*LogEvent{le})
+	c.Assert(sa.errors.Load(), Equals, uint64(1))
+
+	// no handler, the error is printed to stderr once a minute
+	SetAppenderErrorHandler("fail", nil)
+	sa.Append(le)
+	last := sa.lastErrorTime.Load()
+	c.Assert(last, Not(Equals), int64(0))
+	sa.Append(le)
+	c.Assert(sa.lastErrorTime.Load(), Equals, last)
+	c.Assert(sa.errors.Load(), Equals, uint64(3))
+}
+
+func (s *errorHandlerSuite) TestFileAppenderErrors(c *C) {
+	defer removeFiles("____test____log___file4")
+	app, _ := faFactory.NewAppender(map[string]string{"layout": "%m", "fileName": "____test____log___file4"})
+	var events []*LogEvent
+	SetAppenderErrorHandler("file", func(event *LogEvent, err error) { events = append(events, event) })
+	sa := newStatAppender("file", app)
+	fa := app.(*fileAppender)
+	c.Assert(fa.errorHandler, NotNil)
+
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+	c.Assert(sa.Append(le), Equals, true)
+	c.Assert(fa.Flush(context.Background()), IsNil)
+
+	// the appender go routine waits for messages, so the file can be replaced
+	fa.file.Close()
+	fa.writeMsg(faMsg{"m", le})
+	c.Assert(events, DeepEquals, []*LogEvent{le})
+	c.Assert(sa.errors.Load(), Equals, uint64(1))
+	app.Shutdown()
+}
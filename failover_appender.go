@@ -0,0 +1,229 @@
+package log4g
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// log4g the appender registration name
+const failoverAppenderName = "log4g/failoverAppender"
+
+// primary - the name of the appender the events are sent to while it works.
+// This param must be provided when new appender is created
+const FOParamPrimary = "primary"
+
+// secondaries - comma separated list of the appender names the event is sent
+// to in the order, if the primary appender fails to append it.
+// This param must be provided when new appender is created
+const FOParamSecondaries = "secondaries"
+
+// retryInterval - how often the failed primary appender is tried again. The
+// interval is specified in time.ParseDuration() form like 30s or 1m.
+// this parameter is OPTIONAL, default value is 30s
+const FOParamRetryInterval = "retryInterval"
+
+const defaultRetryInterval = 30 * time.Second
+
+// all the params of the appender in the order they are checked
+var foParams = []string{FOParamPrimary, FOParamSecondaries, FOParamRetryInterval}
+
+type failoverAppenderFactory struct {
+}
+
+// failoverAppender sends events to the primary appender. When the primary
+// returns false from Append(), or reports the error of the accepted event
+// later (like the file appender does when it cannot write the file), the event
+// and all following ones are sent to the first secondary appender which
+// accepts them, until the primary is tried again successfully after the
+// retry interval
+type failoverAppender struct {
+	primaryName    string
+	secondaryNames []string
+	retryInterval  time.Duration
+	primary        Appender
+	secondaries    []Appender
+	// the last time the primary failed in nanoseconds, 0 if the primary works
+	failedAt atomic.Int64
+	// number of switches from the primary to the secondaries
+	failovers atomic.Int64
+}
+
+// appenderLinker is implemented by appenders which refer to other appenders
+// by their names. The references are resolved by log4g config when all
+// appenders are created
+type appenderLinker interface {
+	linkAppenders(appenders map[string]Appender) error
+}
+
+func init() {
+	RegisterAppender(&failoverAppenderFactory{})
+}
+
+// The factory allows to create an appender instances
+func (faf *failoverAppenderFactory) Name() string {
+	return failoverAppenderName
+}
+
+func (faf *failoverAppenderFactory) NewAppender(params map[string]string) (Appender, error) {
+	app, errs := parseFailoverParams(params)
+	for _, param := range foParams {
+		if err, ok := errs[param]; ok {
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+// ParamsChecker implementation
+func (faf *failoverAppenderFactory) Params() []string {
+	return foParams
+}
+
+// ParamsChecker implementation
+func (faf *failoverAppenderFactory) CheckParams(params map[string]string) map[string]error {
+	_, errs := parseFailoverParams(params)
+	return errs
+}
+
+// appenderReferrer implementation
+func (faf *failoverAppenderFactory) referredAppenders(params map[string]string) map[string][]string {
+	app, _ := parseFailoverParams(params)
+	refs := make(map[string][]string)
+	if app.primaryName != "" {
+		refs[FOParamPrimary] = []string{app.primaryName}
+	}
+	if len(app.secondaryNames) > 0 {
+		refs[FOParamSecondaries] = app.secondaryNames
+	}
+	return refs
+}
+
+// parseFailoverParams returns not linked appender with the params applied, or
+// the errors of the params by their names
+func parseFailoverParams(params map[string]string) (*failoverAppender, map[string]error) {
+	app := &failoverAppender{retryInterval: defaultRetryInterval}
+	errs := make(map[string]error)
+
+	app.primaryName = strings.Trim(params[FOParamPrimary], " ")
+	if len(app.primaryName) == 0 {
+		errs[FOParamPrimary] = errors.New("Cannot create failover appender: " + FOParamPrimary + " should be specified")
+	}
+
+	for _, name := range strings.Split(params[FOParamSecondaries], ",") {
+		name = strings.Trim(name, " ")
+		if len(name) > 0 {
+			app.secondaryNames = append(app.secondaryNames, name)
+		}
+	}
+	if len(app.secondaryNames) == 0 {
+		errs[FOParamSecondaries] = errors.New("Cannot create failover appender: " + FOParamSecondaries +
+			" should be specified")
+	}
+
+	if value := strings.Trim(params[FOParamRetryInterval], " "); len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			errs[FOParamRetryInterval] = errors.New("Invalid " + FOParamRetryInterval + " value \"" + value +
+				"\", expected positive duration like 30s")
+		} else {
+			app.retryInterval = d
+		}
+	}
+	return app, errs
+}
+
+func (faf *failoverAppenderFactory) Shutdown() {
+	// do nothing here, the appenders are shut down by log config
+}
+
+// appenderLinker implementation
+func (fa *failoverAppender) linkAppenders(appenders map[string]Appender) error {
+	var err error
+	if fa.primary, err = getLinkedAppender(fa.primaryName, appenders); err != nil {
+		return err
+	}
+	fa.secondaries = make([]Appender, len(fa.secondaryNames))
+	for i, name := range fa.secondaryNames {
+		if fa.secondaries[i], err = getLinkedAppender(name, appenders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlePrimaryErrors makes the asynchronous errors of the primary appender
+// be failed over. It is called when the config is installed, because the
+// primary can be used by the current config
+func (fa *failoverAppender) handlePrimaryErrors() {
+	if sa, ok := fa.primary.(*statAppender); ok {
+		sa.setFailoverHandler(fa.onPrimaryError)
+	}
+}
+
+func getLinkedAppender(name string, appenders map[string]Appender) (Appender, error) {
+	a, ok := appenders[name]
+	if !ok {
+		return nil, errors.New("Failover appender refers to undefined appender name \"" + name + "\"")
+	}
+	if sa, ok := a.(*statAppender); ok {
+		if _, ok := sa.Appender.(*failoverAppender); ok {
+			return nil, errors.New("Failover appender cannot refer to another failover appender \"" + name + "\"")
+		}
+	}
+	return a, nil
+}
+
+func (fa *failoverAppender) Append(event *LogEvent) bool {
+	if fa.isPrimaryAvailable() {
+		if fa.primary.Append(event) {
+			fa.failedAt.Store(0)
+			return true
+		}
+		fa.primaryFailed()
+	}
+	return fa.appendToSecondaries(event)
+}
+
+// onPrimaryError is the failoverHandler of the primary appender, it sends
+// the event the primary failed to deliver to the secondaries
+func (fa *failoverAppender) onPrimaryError(event *LogEvent, err error) bool {
+	fa.primaryFailed()
+	return fa.appendToSecondaries(event)
+}
+
+func (fa *failoverAppender) primaryFailed() {
+	if fa.failedAt.Swap(time.Now().UnixNano()) == 0 {
+		fa.failovers.Add(1)
+	}
+}
+
+func (fa *failoverAppender) appendToSecondaries(event *LogEvent) bool {
+	for _, a := range fa.secondaries {
+		if a.Append(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// the primary is tried if it works, or the retry interval is passed since
+// its last failure
+func (fa *failoverAppender) isPrimaryAvailable() bool {
+	failedAt := fa.failedAt.Load()
+	return failedAt == 0 || time.Now().UnixNano()-failedAt >= int64(fa.retryInterval)
+}
+
+func (fa *failoverAppender) Shutdown() {
+	// the primary and secondaries are shut down by log config
+}
+
+// StatsReporter implementation
+func (fa *failoverAppender) Stats() map[string]int64 {
+	var primaryFailed int64
+	if fa.failedAt.Load() != 0 {
+		primaryFailed = 1
+	}
+	return map[string]int64{"failovers": fa.failovers.Load(), "primaryFailed": primaryFailed}
+}
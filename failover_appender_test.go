@@ -0,0 +1,266 @@
+package log4g
+
+import (
+	"context"
+	"errors"
+	. "gopkg.in/check.v1"
+	"path/filepath"
+	"time"
+)
+
+type failoverSuite struct {
+}
+
+var _ = Suite(&failoverSuite{})
+
+// switchAppender fails when ok is false, and counts the appended events
+type switchAppender struct {
+	testAppender
+	ok     bool
+	events int
+}
+
+func (sa *switchAppender) Append(event *LogEvent) bool {
+	if sa.ok {
+		sa.events++
+	}
+	return sa.ok
+}
+
+func newTestFailover(c *C, retry time.Duration, apps ...Appender) *failoverAppender {
+	appenders := map[string]Appender{"p": apps[0]}
+	names := []string{"s1", "s2"}
+	for i, a := range apps[1:] {
+		appenders[names[i]] = a
+	}
+	fa := &failoverAppender{primaryName: "p", secondaryNames: names[:len(apps)-1], retryInterval: retry}
+	c.Assert(fa.linkAppenders(appenders), IsNil)
+	fa.handlePrimaryErrors()
+	return fa
+}
+
+func (s *failoverSuite) TestNewAppender(c *C) {
+	f := &failoverAppenderFactory{}
+	a, err := f.NewAppender(map[string]string{"primary": " p ", "secondaries": "s1, s2,"})
+	c.Assert(err, IsNil)
+	fa := a.(*failoverAppender)
+	c.Assert(fa.primaryName, Equals, "p")
+	c.Assert(fa.secondaryNames, DeepEquals, []string{"s1", "s2"})
+	c.Assert(fa.retryInterval, Equals, defaultRetryInterval)
+
+	a, err = f.NewAppender(map[string]string{"primary": "p", "secondaries": "s1", "retryInterval": "1m"})
+	c.Assert(err, IsNil)
+	c.Assert(a.(*failoverAppender).retryInterval, Equals, time.Minute)
+
+	_, err = f.NewAppender(map[string]string{"secondaries": "s1"})
+	c.Assert(err, NotNil)
+	_, err = f.NewAppender(map[string]string{"primary": "p", "secondaries": " , "})
+	c.Assert(err, NotNil)
+	_, err = f.NewAppender(map[string]string{"primary": "p", "secondaries": "s1", "retryInterval": "-1s"})
+	c.Assert(err, NotNil)
+	_, err = f.NewAppender(map[string]string{"primary": "p", "secondaries": "s1", "retryInterval": "1"})
+	c.Assert(err, NotNil)
+}
+
+func (s *failoverSuite) TestFailover(c *C) {
+	p, s1, s2 := &switchAppender{ok: true}, &switchAppender{}, &switchAppender{ok: true}
+	fa := newTestFailover(c, time.Hour, p, s1, s2)
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(p.events, Equals, 1)
+
+	p.ok = false
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(s2.events, Equals, 2)
+	c.Assert(fa.Stats(), DeepEquals, map[string]int64{"failovers": 1, "primaryFailed": 1})
+
+	// the primary is not tried until the retry interval passes
+	p.ok = true
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(p.events, Equals, 1)
+	c.Assert(s2.events, Equals, 3)
+
+	s2.ok = false
+	c.Assert(fa.Append(le), Equals, false)
+}
+
+func (s *failoverSuite) TestSwitchBack(c *C) {
+	p, s1 := &switchAppender{}, &switchAppender{ok: true}
+	fa := newTestFailover(c, time.Millisecond, p, s1)
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(s1.events, Equals, 1)
+
+	p.ok = true
+	time.Sleep(2 * time.Millisecond)
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(p.events, Equals, 1)
+	c.Assert(s1.events, Equals, 1)
+	c.Assert(fa.Stats(), DeepEquals, map[string]int64{"failovers": 1, "primaryFailed": 0})
+}
+
+func (s *failoverSuite) TestConfig(c *C) {
+	lc := newLogConfig()
+	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	c.Assert(lc.registerAppender(&failoverAppenderFactory{}), IsNil)
+	lc.createAppenders(map[string]string{
+		"appender.PP.type":          consoleAppenderName,
+		"appender.SS.type":          consoleAppenderName,
+		"appender.FF.type":          failoverAppenderName,
+		"appender.FF.primary":       "PP",
+		"appender.FF.secondaries":   "SS",
+		"appender.FF.retryInterval": "10s"})
+	fa := lc.appenders["FF"].(*statAppender).Appender.(*failoverAppender)
+	c.Assert(fa.primary, Equals, lc.appenders["PP"])
+	c.Assert(fa.secondaries[0], Equals, lc.appenders["SS"])
+
+	pnc := checkPanic(func() {
+		lc := newLogConfig()
+		lc.registerAppender(&failoverAppenderFactory{})
+		lc.createAppenders(map[string]string{"appender.FF.type": failoverAppenderName,
+			"appender.FF.primary": "PP", "appender.FF.secondaries": "SS"})
+	})
+	c.Assert(pnc, Equals, true)
+
+	pnc = checkPanic(func() {
+		lc := newLogConfig()
+		lc.registerAppender(&failoverAppenderFactory{})
+		lc.createAppenders(map[string]string{"appender.FF.type": failoverAppenderName,
+			"appender.FF.primary": "GG", "appender.FF.secondaries": "FF",
+			"appender.GG.type":    failoverAppenderName,
+			"appender.GG.primary": "FF", "appender.GG.secondaries": "FF"})
+	})
+	c.Assert(pnc, Equals, true)
+}
+
+// reportingAppender accepts all events and reports their errors later
+// through the error handler like the file appender does
+type reportingAppender struct {
+	testAppender
+	handler ErrorHandler
+}
+
+func (ra *reportingAppender) Append(event *LogEvent) bool {
+	return true
+}
+
+func (ra *reportingAppender) SetErrorHandler(handler ErrorHandler) {
+	ra.handler = handler
+}
+
+func (s *failoverSuite) TestAsyncPrimaryError(c *C) {
+	ra := &reportingAppender{}
+	p, s1 := newStatAppender("p", ra), &switchAppender{ok: true}
+	fa := newTestFailover(c, time.Hour, p, s1)
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+
+	c.Assert(fa.Append(le), Equals, true)
+	ra.handler(le, errors.New("no space left on device"))
+	c.Assert(s1.events, Equals, 1)
+	c.Assert(fa.Stats(), DeepEquals, map[string]int64{"failovers": 1, "primaryFailed": 1})
+	// the error handled by the failover is not counted by the primary
+	c.Assert(p.errors.Load(), Equals, uint64(0))
+
+	// the primary is not used until the retry interval passes
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(s1.events, Equals, 2)
+	c.Assert(p.appends.Load(), Equals, uint64(1))
+
+	// the event is lost if no secondary accepts it
+	s1.ok = false
+	ra.handler(le, errors.New("no space left on device"))
+	c.Assert(p.errors.Load(), Equals, uint64(1))
+
+	// the errors with no event are not failed over
+	ra.handler(nil, errors.New("cannot reopen file"))
+	c.Assert(s1.events, Equals, 2)
+	c.Assert(p.errors.Load(), Equals, uint64(2))
+
+	// the failed Append() of the primary is counted by the failover appender only
+	sp := newStatAppender("sp", &switchAppender{})
+	fa = newTestFailover(c, time.Hour, sp, &switchAppender{ok: true})
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(sp.errors.Load(), Equals, uint64(0))
+}
+
+func (s *failoverSuite) TestFileAppenderOpenError(c *C) {
+	app, err := faFactory.NewAppender(map[string]string{"layout": "%m",
+		"fileName": filepath.Join(c.MkDir(), "missing", "app.log"), "buffer": "1"})
+	c.Assert(err, IsNil)
+	p, s1 := newStatAppender("p", app), &switchAppender{ok: true}
+	defer p.Shutdown()
+	fa := newTestFailover(c, time.Hour, p, s1)
+
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "a", Payload: "m"}
+	c.Assert(fa.Append(le), Equals, true)
+	c.Assert(p.Flush(context.Background()), IsNil)
+	c.Assert(s1.events, Equals, 1)
+
+	// the primary is not used after the failure
+	for i := 0; i < 4; i++ {
+		c.Assert(fa.Append(le), Equals, true)
+	}
+	c.Assert(s1.events, Equals, 5)
+	c.Assert(p.appends.Load(), Equals, uint64(1))
+	c.Assert(fa.Stats()["failovers"], Equals, int64(1))
+}
+
+func (s *failoverSuite) TestRelinkConfig(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	c.Assert(m.registerAppender(&failoverAppenderFactory{}), IsNil)
+	params := map[string]string{
+		"appender.PP.type":        consoleAppenderName,
+		"appender.SS.type":        consoleAppenderName,
+		"appender.FF.type":        failoverAppenderName,
+		"appender.FF.primary":     "PP",
+		"appender.FF.secondaries": "SS",
+		"context.appenders":       "FF"}
+	c.Assert(m.setNewProperties(params), IsNil)
+	pp := m.config.appenders["PP"].(*statAppender)
+	c.Assert(pp.failover.Load(), NotNil)
+
+	// the reused primary is not bound to the removed failover appender
+	delete(params, "appender.FF.type")
+	delete(params, "appender.FF.primary")
+	delete(params, "appender.FF.secondaries")
+	params["context.appenders"] = "PP"
+	c.Assert(m.setNewProperties(params), IsNil)
+	c.Assert(m.config.appenders["PP"], Equals, pp)
+	c.Assert(pp.failover.Load(), IsNil)
+}
+
+func (s *failoverSuite) TestFailedConfig(c *C) {
+	m := &logManager{config: newLogConfig()}
+	defer m.shutdown(context.Background())
+	saf := &shutdownAppenderFactory{}
+	c.Assert(m.registerAppender(saf), IsNil)
+	c.Assert(m.registerAppender(&failoverAppenderFactory{}), IsNil)
+	params := map[string]string{
+		"appender.PP.type":        shutdownAppenderName,
+		"appender.SS.type":        shutdownAppenderName,
+		"appender.FF.type":        failoverAppenderName,
+		"appender.FF.primary":     "PP",
+		"appender.FF.secondaries": "SS",
+		"context.appenders":       "FF"}
+	c.Assert(m.setNewProperties(params), IsNil)
+	config := m.config
+	pp := config.appenders["PP"].(*statAppender)
+	handler := pp.failover.Load()
+	c.Assert(handler, NotNil)
+
+	// the failed config doesn't relink the reused primary, and its own
+	// appenders are shut down
+	params["appender.SS.p"] = "1"
+	params["logger.a.level"] = "NOPE"
+	c.Assert(m.setNewProperties(params), NotNil)
+	c.Assert(m.config, Equals, config)
+	c.Assert(pp.failover.Load(), Equals, handler)
+	c.Assert(pp.Appender.(*shutdownAppender).shutdown, Equals, false)
+	c.Assert(config.appenders["SS"].(*statAppender).Appender.(*shutdownAppender).shutdown, Equals, false)
+	c.Assert(saf.created, HasLen, 3)
+	c.Assert(saf.created[2].shutdown, Equals, true)
+}
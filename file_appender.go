@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/dspasibenko/log4g/collections"
@@ -10,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -56,6 +58,14 @@ const FAParamRotate = "rotate"
 // daily: rotate every new day (the host time midnight) or maxFileSize OR maxLines is reached
 var rotateState = map[string]int{"none": rsNone, "size": rsSize, "daily": rsDaily}
 
+// all the params of the appender in the order they are checked
+var faParams = append([]string{FAParamLayout, FAParamFileName, FAParamFileBuffer, FAParamFileAppend, FAParamMaxSize,
+	FAParamMaxDiskSpace, FAParamRotate}, layoutParams...)
+
+// fileOpenRetryInterval is the time after a failed open of the file before
+// the appender tries to open it again
+var fileOpenRetryInterval = time.Second
+
 const (
 	rsNone = iota
 	rsSize
@@ -66,7 +76,9 @@ type fileAppenderFactory struct {
 }
 
 type fileAppender struct {
-	msgChannel     chan string
+	msgChannel     chan faMsg
+	flushCh        chan chan bool
+	reopenCh       chan chan bool
 	controlCh      chan bool
 	fileName       string
 	file           *os.File
@@ -76,6 +88,20 @@ type fileAppender struct {
 	maxDiskSpace   int64
 	rotate         int
 	stat           stats
+	// reports write errors, the errors are printed to stderr if it is nil
+	errorHandler ErrorHandler
+	// copies of the stat values, which can be read by Stats() concurrently
+	// with the appender go routine
+	written   atomic.Int64
+	chunkSize atomic.Int64
+	rotations atomic.Int64
+}
+
+// the formatted message and the event it was built from, which is passed to
+// the error handler if the message cannot be written
+type faMsg struct {
+	msg   string
+	event *LogEvent
 }
 
 type stats struct {
@@ -85,6 +111,11 @@ type stats struct {
 	size          int64
 	startTime     time.Time
 	lastErrorTime time.Time
+
+	// the last error of opening the file and the time when the open is
+	// retried, so the file system is not touched for every message
+	openErr       error
+	openRetryTime time.Time
 }
 
 type chunkInfo struct {
@@ -110,84 +141,113 @@ func (faf *fileAppenderFactory) Name() string {
 }
 
 func (faf *fileAppenderFactory) NewAppender(params map[string]string) (Appender, error) {
-	layout, ok := params[FAParamLayout]
-	if !ok || len(layout) == 0 {
-		return nil, errors.New("Cannot create file appender: layout should be specified")
+	app, errs := parseFileAppenderParams(params)
+	for _, param := range faParams {
+		if err, ok := errs[param]; ok {
+			return nil, err
+		}
 	}
 
-	fileName, ok := params[FAParamFileName]
-	if !ok || len(fileName) == 0 {
-		return nil, errors.New("Cannot create file appender: file should be specified")
+	app.flushCh = make(chan chan bool)
+	app.reopenCh = make(chan chan bool)
+	app.controlCh = make(chan bool, 1)
+	app.stat.chunks, app.stat.chunksSize = app.getLogChunks()
+
+	go func() {
+		defer app.close()
+		app.stat.startTime = time.Now()
+		for {
+			select {
+			case m, ok := <-app.msgChannel:
+				if !ok {
+					return
+				}
+				app.onMsg(m)
+			case req := <-app.flushCh:
+				app.drain()
+				close(req)
+			case req := <-app.reopenCh:
+				app.drain()
+				app.reopenFile()
+				close(req)
+			}
+		}
+	}()
+	return app, nil
+}
+
+// ParamsChecker implementation
+func (faf *fileAppenderFactory) Params() []string {
+	return faParams
+}
+
+// ParamsChecker implementation
+func (faf *fileAppenderFactory) CheckParams(params map[string]string) map[string]error {
+	_, errs := parseFileAppenderParams(params)
+	return errs
+}
+
+// parseFileAppenderParams returns not started appender with the params
+// applied, or the errors of the params by their names
+func parseFileAppenderParams(params map[string]string) (*fileAppender, map[string]error) {
+	app := &fileAppender{}
+	errs := make(map[string]error)
+
+	layoutTemplate, err := parseLayoutParams(params[FAParamLayout], params)
+	if err == errNoLayout {
+		errs[FAParamLayout] = errors.New("Cannot create file appender: layout should be specified")
+	} else if err != nil {
+		errs[FAParamLayout] = errors.New("Cannot create file appender, incorrect layout: " + err.Error())
+	} else {
+		app.layoutTemplate = layoutTemplate
 	}
 
-	layoutTemplate, err := ParseLayout(layout)
-	if err != nil {
-		return nil, errors.New("Cannot create file appender, incorrect layout: " + err.Error())
+	var ok bool
+	app.fileName, ok = params[FAParamFileName]
+	if !ok || len(app.fileName) == 0 {
+		errs[FAParamFileName] = errors.New("Cannot create file appender: file should be specified")
 	}
 
 	buffer, err := ParseInt(params[FAParamFileBuffer], 1, 10000, 100)
 	if err != nil {
-		return nil, errors.New("Invalid " + FAParamFileBuffer + " value: " + err.Error())
+		errs[FAParamFileBuffer] = errors.New("Invalid " + FAParamFileBuffer + " value: " + err.Error())
+	} else {
+		app.msgChannel = make(chan faMsg, buffer)
 	}
 
-	fileAppend, err := ParseBool(params[FAParamFileAppend], true)
+	app.fileAppend, err = ParseBool(params[FAParamFileAppend], true)
 	if err != nil {
-		return nil, errors.New("Invalid " + FAParamFileAppend + " value: " + err.Error())
+		errs[FAParamFileAppend] = errors.New("Invalid " + FAParamFileAppend + " value: " + err.Error())
 	}
 
-	maxFileSize, err := ParseInt64(params[FAParamMaxSize], 1000, maxInt64, maxInt64)
+	app.maxSize, err = ParseInt64(params[FAParamMaxSize], 1000, maxInt64, maxInt64)
 	if err != nil {
-		return nil, errors.New("Invalid " + FAParamMaxSize + " value: " + err.Error())
+		errs[FAParamMaxSize] = errors.New("Invalid " + FAParamMaxSize + " value: " + err.Error())
 	}
 
-	maxDiskSpace, err := ParseInt64(params[FAParamMaxDiskSpace], 2000, maxInt64, maxInt64)
+	app.maxDiskSpace, err = ParseInt64(params[FAParamMaxDiskSpace], 2000, maxInt64, maxInt64)
 	if err != nil {
-		return nil, errors.New("Invalid " + FAParamMaxDiskSpace + " value: " + err.Error())
+		errs[FAParamMaxDiskSpace] = errors.New("Invalid " + FAParamMaxDiskSpace + " value: " + err.Error())
 	}
 
 	rotateStr, ok := params[FAParamRotate]
 	rotateStr = strings.Trim(rotateStr, " ")
-	rState := rsNone
+	app.rotate = rsNone
 	if ok && len(rotateStr) > 0 {
-		rState, ok = rotateState[rotateStr]
+		app.rotate, ok = rotateState[rotateStr]
 		if !ok {
-			return nil, errors.New("Unknown rotate state \"" + rotateStr +
+			errs[FAParamRotate] = errors.New("Unknown rotate state \"" + rotateStr +
 				"\", expected \"none\", \"size\", or \"daily \" value")
 		}
 	}
 
-	if maxDiskSpace/2 < maxFileSize && rState != rsNone {
-		return nil, errors.New("Invalid " + FAParamMaxDiskSpace +
+	_, sizeErr := errs[FAParamMaxSize]
+	_, spaceErr := errs[FAParamMaxDiskSpace]
+	if !sizeErr && !spaceErr && app.maxDiskSpace/2 < app.maxSize && app.rotate != rsNone {
+		errs[FAParamMaxDiskSpace] = errors.New("Invalid " + FAParamMaxDiskSpace +
 			" value. It should be at least twice bigger than " + FAParamMaxSize)
 	}
-
-	app := &fileAppender{}
-	app.msgChannel = make(chan string, buffer)
-	app.controlCh = make(chan bool, 1)
-	app.layoutTemplate = layoutTemplate
-	app.fileName = fileName
-	app.fileAppend = fileAppend
-	app.maxSize = maxFileSize
-	app.maxDiskSpace = maxDiskSpace
-	app.rotate = rState
-	app.stat.chunks, app.stat.chunksSize = app.getLogChunks()
-
-	go func() {
-		defer app.close()
-		app.stat.startTime = time.Now()
-		for {
-			str, ok := <-app.msgChannel
-			if !ok {
-				break
-			}
-
-			if app.isRotationNeeded() {
-				app.rotateFile()
-			}
-			app.writeMsg(str)
-		}
-	}()
-	return app, nil
+	return app, errs
 }
 
 func (faf *fileAppenderFactory) Shutdown() {
@@ -198,17 +258,75 @@ func (fa *fileAppender) Append(event *LogEvent) (ok bool) {
 	ok = false
 	defer EndQuietly()
 	msg := ToLogMessage(event, fa.layoutTemplate)
-	fa.msgChannel <- msg
+	fa.msgChannel <- faMsg{msg, event}
 	ok = true
 	return ok
 }
 
+// Flusher interface implementation
+func (fa *fileAppender) Flush(ctx context.Context) error {
+	return requestFlush(ctx, fa.flushCh, fa.controlCh)
+}
+
+// fileReopener implementation
+func (fa *fileAppender) reopen(ctx context.Context) error {
+	return requestFlush(ctx, fa.reopenCh, fa.controlCh)
+}
+
 func (fa *fileAppender) Shutdown() {
 	close(fa.msgChannel)
 	<-fa.controlCh
 }
 
+// queuedCounter implementation
+func (fa *fileAppender) queued() int {
+	return len(fa.msgChannel)
+}
+
+// ErrorReporter implementation. Must be called before the first Append()
+func (fa *fileAppender) SetErrorHandler(handler ErrorHandler) {
+	fa.errorHandler = handler
+}
+
+// StatsReporter implementation
+func (fa *fileAppender) Stats() map[string]int64 {
+	return map[string]int64{"bytesWritten": fa.written.Load(), "chunkSize": fa.chunkSize.Load(),
+		"rotations": fa.rotations.Load()}
+}
+
+// Called from the appender go routine
+func (fa *fileAppender) onMsg(m faMsg) {
+	if fa.isRotationNeeded() {
+		if err := fa.rotateFile(); err != nil {
+			fa.onError(m.event, err)
+			return
+		}
+	}
+	fa.writeMsg(m)
+}
+
+// writes messages which are in the channel buffer at the moment
+func (fa *fileAppender) drain() {
+	for {
+		select {
+		case m, ok := <-fa.msgChannel:
+			if !ok {
+				return
+			}
+			fa.onMsg(m)
+		default:
+			return
+		}
+	}
+}
+
+// rotateFile archives the current file and opens a new one. If the file
+// cannot be opened, the messages fail with the open error until
+// fileOpenRetryInterval is passed, and then the open is tried again
 func (fa *fileAppender) rotateFile() error {
+	if fa.file == nil && fa.stat.openErr != nil && time.Now().Before(fa.stat.openRetryTime) {
+		return fa.stat.openErr
+	}
 	fa.archiveCurrent()
 
 	fa.stat.size = 0
@@ -222,15 +340,46 @@ func (fa *fileAppender) rotateFile() error {
 		}
 	}
 
+	fa.chunkSize.Store(fa.stat.size)
+
 	fd, err := os.OpenFile(fa.fileName, flags, 0660)
 	if err != nil {
-		panic("File Appender cannot open file " + fa.fileName + " to store logs: " + err.Error())
+		fa.stat.openErr = fmt.Errorf("cannot open file \"%s\" to store logs: %w", fa.fileName, err)
+		fa.stat.openRetryTime = time.Now().Add(fileOpenRetryInterval)
+		return fa.stat.openErr
 	}
 	fa.file = fd
+	fa.stat.openErr = nil
 
 	return nil
 }
 
+// reopenFile closes the current file and opens the file with fileName
+// again, which could be moved by an external tool like logrotate. The new
+// messages are appended to the file if it exists. If the file cannot be
+// opened, the current one is kept and the error is reported to the error
+// handler with nil event.
+func (fa *fileAppender) reopenFile() {
+	if fa.file == nil {
+		// the file is opened by the first message
+		return
+	}
+
+	fd, err := os.OpenFile(fa.fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		fa.onError(nil, fmt.Errorf("cannot reopen file \"%s\": %w", fa.fileName, err))
+		return
+	}
+	fa.file.Close()
+	fa.file = fd
+
+	fa.stat.size = 0
+	if fInfo, err := fd.Stat(); err == nil {
+		fa.stat.size = fInfo.Size()
+	}
+	fa.chunkSize.Store(fa.stat.size)
+}
+
 func (fa *fileAppender) archiveCurrent() {
 	// if there is no file, or it is the first visit of the method for the appender
 	// and we would like to continue write to the same file...
@@ -262,6 +411,7 @@ func (fa *fileAppender) archiveCurrent() {
 
 	fa.stat.chunks.Add(&chunkInfo{id, archiveName, fa.stat.size})
 	fa.stat.chunksSize += fa.stat.size
+	fa.rotations.Add(1)
 }
 
 func (fa *fileAppender) cutChunks() {
@@ -334,21 +484,33 @@ func (fa *fileAppender) timeRotation() bool {
 	return fa.stat.startTime.Day() != now.Day() || now.Sub(fa.stat.startTime) > time.Hour*24
 }
 
-func (fa *fileAppender) writeMsg(msg string) {
-	n, err := fmt.Fprint(fa.file, msg, "\n")
+func (fa *fileAppender) writeMsg(m faMsg) {
+	n, err := fmt.Fprint(fa.file, m.msg, "\n")
 
 	if err != nil {
-		if time.Since(fa.stat.lastErrorTime) > time.Minute {
-			fa.stat.lastErrorTime = time.Now()
-			fmt.Fprintf(os.Stderr, "File appender %+v: %s\n", fa, err)
-		}
+		fa.onError(m.event, err)
 		return
 	}
 
 	fa.stat.size += int64(n)
+	fa.written.Add(int64(n))
+	fa.chunkSize.Store(fa.stat.size)
 	fa.cutChunks()
 }
 
+// onError passes the error to the error handler, or prints it to stderr not
+// more often than once a minute if there is no handler
+func (fa *fileAppender) onError(event *LogEvent, err error) {
+	if fa.errorHandler != nil {
+		fa.errorHandler(event, err)
+		return
+	}
+	if time.Since(fa.stat.lastErrorTime) > time.Minute {
+		fa.stat.lastErrorTime = time.Now()
+		fmt.Fprintf(os.Stderr, "File appender %+v: %s\n", fa, err)
+	}
+}
+
 func (fa *fileAppender) close() {
 	err := recover()
 	if err != nil {
@@ -1,6 +1,8 @@
 package log4g
 
 import (
+	"context"
+	"errors"
 	. "gopkg.in/check.v1"
 	"io/ioutil"
 	"os"
@@ -98,14 +100,16 @@ func (s *faConfigSuite) TestShutdown(c *C) {
 
 func (s *faConfigSuite) TestAppendDiskSpace(c *C) {
 	defer removeFiles("456____test____log___file")
-	fa := writeLogs(c, map[string]string{"layout": "%p", "fileName": "456____test____log___file", "buffer": "1000",
+	fa := writeLogs(c, map[string]string{"layout": "%-5p", "fileName": "456____test____log___file", "buffer": "1000",
 		"maxFileSize": "1K", "maxDiskSpace": "10K", "rotate": "size"}, 5000)
 	c.Check(fa.stat.chunksSize >= 9000 && fa.stat.chunksSize <= 10000, Equals, true)
+	c.Check(fa.Stats()["rotations"] > 9, Equals, true)
+	c.Check(fa.Stats()["bytesWritten"], Equals, int64(5000*6))
 }
 
 func (s *faConfigSuite) TestAppendToExistingOne(c *C) {
 	defer removeFiles("____test____log___file")
-	params := map[string]string{"layout": "%p", "fileName": "____test____log___file", "buffer": "1000",
+	params := map[string]string{"layout": "%-5p", "fileName": "____test____log___file", "buffer": "1000",
 		"maxFileSize": "2Gib", "rotate": "none", "append": "true"}
 	fa := writeLogs(c, params, 10000)
 	size := fa.stat.size
@@ -116,6 +120,98 @@ func (s *faConfigSuite) TestAppendToExistingOne(c *C) {
 	c.Check(fa.stat.size, Equals, size)
 }
 
+func (s *faConfigSuite) TestReopen(c *C) {
+	fileName := filepath.Join(c.MkDir(), "app.log")
+	app, err := faFactory.NewAppender(map[string]string{"layout": "%m", "fileName": fileName})
+	c.Assert(err, IsNil)
+	fa := app.(*fileAppender)
+	defer fa.Shutdown()
+
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "before"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(os.Rename(fileName, fileName+".1"), IsNil)
+
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "moved"})
+	c.Assert(fa.reopen(context.Background()), IsNil)
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "after"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+
+	data, err := os.ReadFile(fileName + ".1")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "before\nmoved\n")
+	data, err = os.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "after\n")
+	c.Assert(fa.Stats()["chunkSize"], Equals, int64(6))
+}
+
+func (s *faConfigSuite) TestOpenFailure(c *C) {
+	defer func(d time.Duration) { fileOpenRetryInterval = d }(fileOpenRetryInterval)
+	fileOpenRetryInterval = time.Hour
+
+	dir := filepath.Join(c.MkDir(), "logs")
+	fileName := filepath.Join(dir, "app.log")
+	app, err := faFactory.NewAppender(map[string]string{"layout": "%m", "fileName": fileName, "buffer": "1"})
+	c.Assert(err, IsNil)
+	fa := app.(*fileAppender)
+	defer fa.Shutdown()
+	var failed []*LogEvent
+	fa.SetErrorHandler(func(event *LogEvent, err error) {
+		c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+		failed = append(failed, event)
+	})
+
+	// the appender doesn't block when its buffer is full
+	for i := 0; i < 10; i++ {
+		c.Assert(fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "lost"}), Equals, true)
+	}
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(failed, HasLen, 10)
+
+	// the open is not retried until the retry interval passes
+	c.Assert(os.Mkdir(dir, 0770), IsNil)
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "lost"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(failed, HasLen, 11)
+
+	fa.stat.openRetryTime = time.Time{}
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "written"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(failed, HasLen, 11)
+	data, err := os.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "written\n")
+}
+
+func (s *faConfigSuite) TestReopenFailure(c *C) {
+	dir := filepath.Join(c.MkDir(), "logs")
+	c.Assert(os.Mkdir(dir, 0770), IsNil)
+	fileName := filepath.Join(dir, "app.log")
+	app, err := faFactory.NewAppender(map[string]string{"layout": "%m", "fileName": fileName})
+	c.Assert(err, IsNil)
+	fa := app.(*fileAppender)
+	defer fa.Shutdown()
+	var errs []error
+	fa.SetErrorHandler(func(event *LogEvent, err error) {
+		c.Check(event, IsNil)
+		errs = append(errs, err)
+	})
+
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "before"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(os.Rename(dir, dir+".1"), IsNil)
+
+	// the current file is kept if the file cannot be reopened
+	c.Assert(fa.reopen(context.Background()), IsNil)
+	c.Assert(errs, HasLen, 1)
+	fa.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), Payload: "after"})
+	c.Assert(fa.Flush(context.Background()), IsNil)
+	c.Assert(errs, HasLen, 1)
+	data, err := os.ReadFile(filepath.Join(dir+".1", "app.log"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "before\nafter\n")
+}
+
 func (s *faConfigSuite) TestSizeRotation(c *C) {
 	app, _ := faFactory.NewAppender(map[string]string{"layout": " %p", "fileName": "fn", "buffer": "1000",
 		"maxFileSize": "2K", "masDiskSpace": "10K", "rotate": "daily"})
@@ -152,7 +248,7 @@ func (s *faConfigSuite) TestisRotationNeededNone(c *C) {
 	fa := app.(*fileAppender)
 	c.Check(fa.isRotationNeeded(), Equals, true)
 
-	app.Append(&LogEvent{INFO, time.Now(), "abc", "def"})
+	app.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "abc", Payload: "def"})
 	for fa.file == nil {
 		time.Sleep(time.Millisecond)
 	}
@@ -173,7 +269,7 @@ func (s *faConfigSuite) TestisRotationNeededSize(c *C) {
 	fa := app.(*fileAppender)
 	c.Check(fa.isRotationNeeded(), Equals, true)
 
-	app.Append(&LogEvent{INFO, time.Now(), "abc", "def"})
+	app.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "abc", Payload: "def"})
 	for fa.file == nil {
 		time.Sleep(time.Millisecond)
 	}
@@ -194,7 +290,7 @@ func (s *faConfigSuite) TestisRotationNeededDaily(c *C) {
 	fa := app.(*fileAppender)
 	c.Check(fa.isRotationNeeded(), Equals, true)
 
-	app.Append(&LogEvent{INFO, time.Now(), "abc", "def"})
+	app.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "abc", Payload: "def"})
 	for fa.file == nil {
 		time.Sleep(time.Millisecond)
 	}
@@ -208,6 +304,25 @@ func (s *faConfigSuite) TestisRotationNeededDaily(c *C) {
 	app.Shutdown()
 }
 
+func (s *faConfigSuite) TestFlush(c *C) {
+	defer removeFiles("____test____log___file3")
+	app, _ := faFactory.NewAppender(map[string]string{"layout": "%m", "fileName": "____test____log___file3",
+		"buffer": "1000", "append": "false"})
+	fa := app.(*fileAppender)
+	for i := 0; i < 100; i++ {
+		app.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "abc", Payload: "def"})
+	}
+	c.Assert(fa.Flush(context.Background()), IsNil)
+
+	data, err := ioutil.ReadFile("____test____log___file3")
+	c.Assert(err, IsNil)
+	c.Assert(len(data), Equals, 400)
+	c.Assert(fa.Stats(), DeepEquals, map[string]int64{"bytesWritten": 400, "chunkSize": 400, "rotations": 0})
+
+	app.Shutdown()
+	c.Assert(fa.Flush(context.Background()), IsNil)
+}
+
 func removeFiles(prefix string) {
 	archiveName, _ := filepath.Abs(prefix)
 	dir := filepath.Dir(archiveName)
@@ -226,7 +341,7 @@ func writeLogs(c *C, params map[string]string, count int) *fileAppender {
 	c.Assert(app, NotNil)
 	fa := app.(*fileAppender)
 	for idx := 0; idx < count; idx++ {
-		app.Append(&LogEvent{INFO, time.Now(), "abc", "def"})
+		app.Append(&LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: "abc", Payload: "def"})
 	}
 	app.Shutdown()
 	return fa
@@ -0,0 +1,423 @@
+package log4g
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// layoutType - appender setting which selects the layout type: "text" (the
+// default) formats the events by the layout setting, "json" writes every
+// event as JSON object and "logfmt" writes the event as key=value pairs.
+// The layout setting is not needed for "json" and "logfmt" types
+const LayoutParamType = "layoutType"
+
+// jsonTimeFormat - the format of JSON timestamp in time.Format() form,
+// default value is 2006-01-02T15:04:05.000Z07:00
+const LayoutParamJSONTimeFormat = "jsonTimeFormat"
+
+// jsonFieldNames - comma separated list of renamed JSON fields in the form
+// <field>=<name>, where the field is one of timestamp, level, logger,
+// message or caller. For example: timestamp=@timestamp,message=msg
+const LayoutParamJSONFieldNames = "jsonFieldNames"
+
+// jsonStaticFields - comma separated list of <name>=<value> pairs which are
+// added to every JSON object, like service=billing,env=prod
+const LayoutParamJSONStaticFields = "jsonStaticFields"
+
+// the layout params supported by console and file appenders in addition to
+// their layout param
+var layoutParams = []string{LayoutParamType, LayoutParamJSONTimeFormat, LayoutParamJSONFieldNames,
+	LayoutParamJSONStaticFields}
+
+// the standard fields of JSON layout
+const (
+	jfTimestamp = "timestamp"
+	jfLevel     = "level"
+	jfLogger    = "logger"
+	jfMessage   = "message"
+	jfCaller    = "caller"
+)
+
+var jsonFields = []string{jfTimestamp, jfLevel, jfLogger, jfMessage, jfCaller}
+
+const defaultJSONTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// returned by parseLayoutParams() when the text layout is not specified
+var errNoLayout = errors.New("layout should be specified")
+
+// jsonLayout writes the log event as one line JSON object with the standard
+// fields followed by the static fields and the event fields
+type jsonLayout struct {
+	timeFormat string
+	// the names of the standard fields
+	names  map[string]string
+	static []Field
+}
+
+var defaultJSONLayout = &jsonLayout{timeFormat: defaultJSONTimeFormat, names: map[string]string{
+	jfTimestamp: jfTimestamp, jfLevel: jfLevel, jfLogger: jfLogger, jfMessage: jfMessage, jfCaller: jfCaller}}
+
+// parseLayoutParams returns the layout template built by the layout and the
+// layout params. %J pieces of the text layout use the JSON params as well
+func parseLayoutParams(layout string, params map[string]string) (LayoutTemplate, error) {
+	jl, err := parseJSONLayout(params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch layoutType := strings.Trim(params[LayoutParamType], " "); layoutType {
+	case "json":
+		return LayoutTemplate{{value: "J", pieceType: lpJSON, json: jl}}, nil
+	case "logfmt":
+		return LayoutTemplate{{value: "logfmt", pieceType: lpLogfmt}}, nil
+	case "", "text":
+	default:
+		return nil, errors.New("Unknown " + LayoutParamType + " \"" + layoutType +
+			"\", expected \"text\", \"json\" or \"logfmt\"")
+	}
+
+	if len(layout) == 0 {
+		return nil, errNoLayout
+	}
+	template, err := ParseLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	for i := range template {
+		if template[i].pieceType == lpJSON {
+			template[i].json = jl
+		}
+	}
+	return template, nil
+}
+
+func parseJSONLayout(params map[string]string) (*jsonLayout, error) {
+	jl := &jsonLayout{timeFormat: defaultJSONTimeFormat, names: make(map[string]string, len(jsonFields))}
+	if tf := strings.Trim(params[LayoutParamJSONTimeFormat], " "); len(tf) > 0 {
+		jl.timeFormat = tf
+	}
+
+	for _, f := range jsonFields {
+		jl.names[f] = f
+	}
+	names, err := parseJSONPairs(params[LayoutParamJSONFieldNames], LayoutParamJSONFieldNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range names {
+		if _, ok := jl.names[p.Key]; !ok {
+			return nil, errors.New("Unknown JSON field \"" + p.Key + "\" in " + LayoutParamJSONFieldNames +
+				", expected one of " + strings.Join(jsonFields, ", "))
+		}
+		jl.names[p.Key] = p.Value.(string)
+	}
+
+	if jl.static, err = parseJSONPairs(params[LayoutParamJSONStaticFields], LayoutParamJSONStaticFields); err != nil {
+		return nil, err
+	}
+	return jl, nil
+}
+
+// parses comma separated list of <key>=<value> pairs
+func parseJSONPairs(value, param string) ([]Field, error) {
+	var result []Field
+	for _, pair := range strings.Split(value, ",") {
+		if len(strings.Trim(pair, " ")) == 0 {
+			continue
+		}
+		idx := strings.Index(pair, "=")
+		if idx <= 0 || len(strings.Trim(pair[:idx], " ")) == 0 {
+			return nil, errors.New("Invalid " + param + " value \"" + pair + "\", expected <name>=<value> pairs")
+		}
+		result = append(result, Field{strings.Trim(pair[:idx], " "), strings.Trim(pair[idx+1:], " ")})
+	}
+	return result, nil
+}
+
+// writes the event as JSON object. The static fields and the event fields
+// with the same names as the fields written before are skipped, and if there
+// are several event fields with the same key, the last one is written
+func (jl *jsonLayout) write(buf *bytes.Buffer, logEvent *LogEvent) {
+	written := make(map[string]bool, len(jsonFields)+len(jl.static)+len(logEvent.Fields))
+	buf.WriteByte('{')
+	writeJSONKey(buf, jl.names[jfTimestamp], written)
+	writeJSONString(buf, logEvent.Timestamp.Format(jl.timeFormat))
+	writeJSONKey(buf, jl.names[jfLevel], written)
+	writeJSONString(buf, eventLevelName(logEvent.Level))
+	writeJSONKey(buf, jl.names[jfLogger], written)
+	writeJSONString(buf, logEvent.LoggerName)
+	writeJSONKey(buf, jl.names[jfMessage], written)
+	writeJSONValue(buf, logEvent.Payload)
+	if logEvent.Caller != nil {
+		writeJSONKey(buf, jl.names[jfCaller], written)
+		writeJSONString(buf, filepath.Base(logEvent.Caller.File)+":"+strconv.Itoa(logEvent.Caller.Line))
+	}
+
+	for _, f := range jl.static {
+		if !written[f.Key] {
+			writeJSONKey(buf, f.Key, written)
+			writeJSONValue(buf, f.Value)
+		}
+	}
+
+	for i, f := range logEvent.Fields {
+		if written[f.Key] || isOverridden(logEvent.Fields, i) {
+			continue
+		}
+		writeJSONKey(buf, f.Key, written)
+		writeJSONValue(buf, f.Value)
+	}
+	buf.WriteByte('}')
+}
+
+// the field is overridden if there is a field with the same key after it
+func isOverridden(fields []Field, idx int) bool {
+	for _, f := range fields[idx+1:] {
+		if f.Key == fields[idx].Key {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the level name, or the level number if the level has no name
+func eventLevelName(level Level) string {
+	names := *logLevelNames.Load()
+	if level >= 0 && int(level) < len(names) && len(names[level]) > 0 {
+		return names[level]
+	}
+	return strconv.Itoa(int(level))
+}
+
+func writeJSONKey(buf *bytes.Buffer, key string, written map[string]bool) {
+	if len(written) > 0 {
+		buf.WriteByte(',')
+	}
+	written[key] = true
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+}
+
+// writes strings, errors and fmt.Stringer values as JSON strings, and other
+// values in their JSON form. The errors and fmt.Stringer values nested in maps,
+// structs and slices are written as strings as well. A value which cannot be
+// marshaled is written as the string made by fmt.Sprint()
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+		return
+	case string:
+		writeJSONString(buf, val)
+		return
+	case error:
+		writeJSONString(buf, val.Error())
+		return
+	case json.Marshaler:
+	case fmt.Stringer:
+		writeJSONString(buf, val.String())
+		return
+	}
+
+	data, err := json.Marshal(jsonSafe(reflect.ValueOf(v), map[uintptr]bool{}))
+	if err != nil {
+		writeJSONString(buf, fmt.Sprint(v))
+		return
+	}
+	buf.Write(data)
+}
+
+// jsonObject is a struct or a map converted by jsonSafe(), which keeps the
+// order of the fields
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	name  string
+	value interface{}
+}
+
+func (jo jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range jo {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(&buf, m.name)
+		buf.WriteByte(':')
+		data, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonSafe returns the value which is marshaled like v, but the errors and
+// fmt.Stringer values nested in v are replaced by their strings, and the
+// pointers and maps referring to themselves are replaced by their addresses.
+// visited contains the pointers and the maps of the current path
+func jsonSafe(v reflect.Value, visited map[uintptr]bool) interface{} {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if text, ok := jsonText(v); ok {
+		return text
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if visited[v.Pointer()] {
+			return fmt.Sprintf("%p", v.Interface())
+		}
+		visited[v.Pointer()] = true
+		defer delete(visited, v.Pointer())
+		return jsonSafe(v.Elem(), visited)
+	case reflect.Map:
+		if visited[v.Pointer()] {
+			return fmt.Sprintf("%p", v.Interface())
+		}
+		visited[v.Pointer()] = true
+		defer delete(visited, v.Pointer())
+		obj := make(jsonObject, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			obj = append(obj, jsonMember{fmt.Sprint(k.Interface()), jsonSafe(v.MapIndex(k), visited)})
+		}
+		sort.Slice(obj, func(i, j int) bool { return obj[i].name < obj[j].name })
+		return obj
+	case reflect.Struct:
+		return appendJSONFields(jsonObject{}, v, visited)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// written as base64 string
+			return v.Interface()
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = jsonSafe(v.Index(i), visited)
+		}
+		return arr
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Sprint(v.Interface())
+	}
+	return v.Interface()
+}
+
+// appends the exported fields of the struct like json.Marshal() writes them,
+// the fields of the embedded structs without json name are appended in place
+func appendJSONFields(obj jsonObject, v reflect.Value, visited map[uintptr]bool) jsonObject {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		fv := v.Field(i)
+		if f.Anonymous && tag[0] == "" {
+			if fv.Kind() == reflect.Pointer && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if _, ok := jsonText(fv); !ok {
+					obj = appendJSONFields(obj, fv, visited)
+					continue
+				}
+			}
+		}
+		if !f.IsExported() || tag[0] == "-" {
+			continue
+		}
+		if containsString(tag[1:], "omitempty") && isEmptyJSONValue(fv) {
+			continue
+		}
+		obj = append(obj, jsonMember{fieldName(f), jsonSafe(fv, visited)})
+	}
+	return obj
+}
+
+// returns the string of the error or the fmt.Stringer value, the methods may
+// have pointer receiver. json.Marshaler values are returned as they are
+func jsonText(v reflect.Value) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if _, ok := v.Interface().(json.Marshaler); ok {
+		if _, ok := v.Interface().(error); !ok {
+			return v.Interface(), true
+		}
+	}
+	if text, ok := logfmtText(v); ok {
+		if err, ok := text.(error); ok {
+			return err.Error(), true
+		}
+		return text.(fmt.Stringer).String(), true
+	}
+	return nil, false
+}
+
+// the value is omitted by omitempty option of json tag
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writes the string as JSON string literal, invalid UTF-8 sequences are
+// replaced by U+FFFD
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(byte(r))
+		case r == '\n':
+			buf.WriteString("\\n")
+		case r == '\r':
+			buf.WriteString("\\r")
+		case r == '\t':
+			buf.WriteString("\\t")
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			buf.WriteString("\\u")
+			for shift := 12; shift >= 0; shift -= 4 {
+				buf.WriteByte(hexDigits[(r>>uint(shift))&0xF])
+			}
+		case r == utf8.RuneError && size == 1:
+			buf.WriteString("\\ufffd")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,135 @@
+package log4g
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	. "gopkg.in/check.v1"
+	"time"
+)
+
+type jsonLayoutSuite struct {
+}
+
+var _ = Suite(&jsonLayoutSuite{})
+
+var jsonTestTime = time.Date(2024, 3, 1, 10, 20, 30, 123000000, time.UTC)
+
+func (s *jsonLayoutSuite) TestJSONLayout(c *C) {
+	t, err := parseLayoutParams("", map[string]string{"layoutType": "json"})
+	c.Assert(err, IsNil)
+
+	le := &LogEvent{Level: INFO, Timestamp: jsonTestTime, LoggerName: "a.b", Payload: "Hello \"JSON\"\n",
+		Fields: []Field{{"id", 12}, {"err", errors.New("failed")}, {"id", 13}, {"d", time.Second}}}
+	c.Assert(ToLogMessage(le, t), Equals, `{"timestamp":"2024-03-01T10:20:30.123Z","level":"INFO",`+
+		`"logger":"a.b","message":"Hello \"JSON\"\n","err":"failed","id":13,"d":"1s"}`)
+
+	le = &LogEvent{Level: WARN, Timestamp: jsonTestTime, Payload: map[string]int{"a": 1},
+		Caller: &CallerInfo{File: "/src/a/b.go", Line: 12}}
+	c.Assert(ToLogMessage(le, t), Equals, `{"timestamp":"2024-03-01T10:20:30.123Z","level":"WARN",`+
+		`"logger":"","message":{"a":1},"caller":"b.go:12"}`)
+}
+
+type jsonTestBase struct {
+	ID int `json:"id"`
+}
+
+type jsonTestNode struct {
+	jsonTestBase
+	Name   string
+	Err    error         `json:"err"`
+	D      time.Duration `json:"d,omitempty"`
+	T      time.Time     `json:"t"`
+	Next   *jsonTestNode `json:"next,omitempty"`
+	hidden int
+}
+
+func (s *jsonLayoutSuite) TestNestedValues(c *C) {
+	check := func(v interface{}, expected string) {
+		var buf bytes.Buffer
+		writeJSONValue(&buf, v)
+		c.Assert(buf.String(), Equals, expected)
+	}
+
+	check(map[string]interface{}{"err": errors.New("failed"), "d": []interface{}{time.Second, 1}},
+		`{"d":["1s",1],"err":"failed"}`)
+	check(map[int]error{2: nil, 1: errors.New("e")}, `{"1":"e","2":null}`)
+	check(&jsonTestNode{jsonTestBase: jsonTestBase{ID: 1}, Name: "a", Err: errors.New("failed"),
+		D: time.Minute, T: jsonTestTime, hidden: 1},
+		`{"id":1,"Name":"a","err":"failed","d":"1m0s","t":"2024-03-01T10:20:30.123Z"}`)
+	check([]byte("ab"), `"YWI="`)
+
+	// the cycles are written as the pointers
+	n := &jsonTestNode{Name: "a"}
+	n.Next = n
+	var buf bytes.Buffer
+	writeJSONValue(&buf, n)
+	c.Assert(buf.String(), Matches, `\{"id":0,"Name":"a","err":null,"t":"0001-01-01T00:00:00Z","next":"0x[0-9a-f]+"\}`)
+	m := map[string]interface{}{"a": 1}
+	m["m"] = m
+	buf.Reset()
+	writeJSONValue(&buf, m)
+	c.Assert(buf.String(), Matches, `\{"a":1,"m":"0x[0-9a-f]+"\}`)
+
+	// the values json.Marshal() doesn't support are written by fmt.Sprint()
+	buf.Reset()
+	writeJSONValue(&buf, map[string]interface{}{"f": func() {}, "c": complex(1, 2)})
+	c.Assert(buf.String(), Matches, `\{"c":"\(1\+2i\)","f":"0x[0-9a-f]+"\}`)
+}
+
+func (s *jsonLayoutSuite) TestJSONParams(c *C) {
+	t, err := parseLayoutParams("", map[string]string{"layoutType": " json ", "jsonTimeFormat": "15:04:05",
+		"jsonFieldNames": "timestamp=@timestamp, message=msg", "jsonStaticFields": "service=billing,msg=x"})
+	c.Assert(err, IsNil)
+	le := &LogEvent{Level: ERROR, Timestamp: jsonTestTime, LoggerName: "a", Payload: "m",
+		Fields: []Field{{"service", "other"}}}
+	c.Assert(ToLogMessage(le, t), Equals,
+		`{"@timestamp":"10:20:30","level":"ERROR","logger":"a","msg":"m","service":"billing"}`)
+
+	_, err = parseLayoutParams("", map[string]string{"layoutType": "xml"})
+	c.Assert(err, NotNil)
+	_, err = parseLayoutParams("", map[string]string{"layoutType": "json", "jsonFieldNames": "time=t"})
+	c.Assert(err, NotNil)
+	_, err = parseLayoutParams("", map[string]string{"layoutType": "json", "jsonStaticFields": "service"})
+	c.Assert(err, NotNil)
+	_, err = parseLayoutParams("", map[string]string{"layoutType": "text"})
+	c.Assert(err, Equals, errNoLayout)
+}
+
+func (s *jsonLayoutSuite) TestJSONPiece(c *C) {
+	t, err := ParseLayout("%d{15:04} %J")
+	c.Assert(err, IsNil)
+	le := &LogEvent{Level: DEBUG, Timestamp: jsonTestTime, LoggerName: "a", Payload: "m"}
+	c.Assert(ToLogMessage(le, t), Equals,
+		`10:20 {"timestamp":"2024-03-01T10:20:30.123Z","level":"DEBUG","logger":"a","message":"m"}`)
+
+	t, err = parseLayoutParams("%p %J", map[string]string{"jsonFieldNames": "level=severity"})
+	c.Assert(err, IsNil)
+	c.Assert(ToLogMessage(le, t), Equals,
+		`DEBUG {"timestamp":"2024-03-01T10:20:30.123Z","severity":"DEBUG","logger":"a","message":"m"}`)
+}
+
+func (s *jsonLayoutSuite) TestEscaping(c *C) {
+	t, _ := ParseLayout("%J")
+	payload := "q\" b\\ t\t c\x01 u\u2028 <&> \xff ok"
+	msg := ToLogMessage(&LogEvent{Level: INFO, Timestamp: jsonTestTime, Payload: payload}, t)
+
+	var obj map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(msg), &obj), IsNil)
+	c.Assert(obj["message"], Equals, "q\" b\\ t\t c\x01 u\u2028 <&> \ufffd ok")
+	c.Assert(msg, Matches, `.*u\\u2028 <&> \\ufffd ok.*`)
+}
+
+func (s *jsonLayoutSuite) TestAppenders(c *C) {
+	a, err := caFactory.NewAppender(map[string]string{"layoutType": "json"})
+	c.Assert(err, IsNil)
+	c.Assert(a.(*consoleAppender).layoutTemplate[0].pieceType, Equals, lpJSON)
+	_, err = caFactory.NewAppender(map[string]string{})
+	c.Assert(err, NotNil)
+
+	_, errs := parseFileAppenderParams(map[string]string{"layoutType": "json", "fileName": "fn"})
+	c.Assert(len(errs), Equals, 0)
+	_, errs = parseFileAppenderParams(map[string]string{"layoutType": "json", "jsonStaticFields": "=1",
+		"fileName": "fn"})
+	c.Assert(errs[FAParamLayout], NotNil)
+}
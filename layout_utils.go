@@ -4,6 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 // layout pieces types
@@ -13,6 +18,13 @@ const (
 	lpDate
 	lpLogLevel
 	lpMessage
+	lpFields
+	lpField
+	lpCallerFile
+	lpCallerLine
+	lpCallerFunction
+	lpJSON
+	lpLogfmt
 )
 
 // parse states
@@ -21,31 +33,90 @@ const (
 	psPiece
 	psDateStart
 	psDate
+	psFieldsStart
+	psField
+	psLoggerNameStart
+	psLoggerName
 )
 
 type layoutPiece struct {
 	value     string
 	pieceType int
+	// the JSON layout settings of lpJSON piece, defaultJSONLayout is used if it is nil
+	json *jsonLayout
+	// the format modifier of the placeholder, nil if it is not specified
+	format *pieceFormat
+	// the logger name abbreviation of lpLoggerName piece, nil if the full name is written
+	name *loggerNameFormat
+}
+
+// loggerNameFormat is the logger name abbreviation like %c{2} or %c{1.}
+type loggerNameFormat struct {
+	// only the last components of the name are written if components > 0
+	components int
+	// the ancestor components are cut to ancestorLen runes if ancestorLen > 0
+	ancestorLen int
+}
+
+// pieceFormat is the format modifier of a placeholder like %-20.30c
+type pieceFormat struct {
+	// the value is padded by spaces up to minWidth runes
+	minWidth  int
+	leftAlign bool
+	// the value is truncated to maxWidth runes if maxWidth > 0. The beginning
+	// of the value is cut off unless truncateEnd is set
+	maxWidth    int
+	truncateEnd bool
 }
 
 type LayoutTemplate []layoutPiece
 
-var logLevelNames []string
+// level names used by ToLogMessage(). The slice is never modified, but
+// replaced as a whole when the names are changed
+var logLevelNames atomic.Pointer[[]string]
 
 func init() {
-	logLevelNames = lm.config.levelNames
+	setLogLevelNames(lm.config.levelNames)
+}
+
+func setLogLevelNames(levelNames []string) {
+	names := make([]string, len(levelNames))
+	copy(names, levelNames)
+	logLevelNames.Store(&names)
 }
 
 // ParseLayout parses the layout parameter and returns LayoutTemplate instance
 // if it is correct.
 // The log message layout is format string with the following placeholders:
 // %c - logger name
+// %c{N} - the last N components of the logger name, like "stripe.client"
+//				for %c{2} and the logger "payments.gateway.adapters.stripe.client"
+// %c{N.} - the logger name with the ancestor components cut to N letters,
+//				like "p.g.a.s.client" for %c{1.}
 // %d{date/time format} - date/time. The date/time format should be specified
 //				in time.Format() form like "Mon, 02 Jan 2006 15:04:05 -0700"
 // %p - priority name
 // %m - the log message
+// %X - all fields of the log event in the form key1=value1 key2=value2 ...
+// %X{key} - value of the log event field with the key, or empty string if
+//				there is no such field
+// %F - source file name of the logging call
+// %L - source line number of the logging call
+// %M - function name of the logging call
+// %J - the whole log event as JSON object, see jsonLayout
 // %% - '%'
 //
+// Every placeholder except %% can have a format modifier between '%' and
+// the placeholder letter in the form [-][minWidth][.[-]maxWidth]:
+// %5p - the value is padded by spaces on the left up to 5 characters
+// %-5p - the value is padded by spaces on the right up to 5 characters
+// %.30c - the value longer than 30 characters is cut off at the beginning
+// %.-30m - the value longer than 30 characters is cut off at the end
+// %-20.20c - the value is padded on the right or truncated to 20 characters
+//
+// %F, %L and %M are empty unless caller location capturing is enabled for
+// the logger or its context.
+//
 // For example, layout string '[%d{01-02 15:04:05.000}] %p %c: %m' will be parsed
 // to a layout template, which can be used by ToLogMessage() to form the log
 // line. For logger 'a.b.c' will produce message like this:
@@ -56,26 +127,50 @@ func ParseLayout(layout string) (LayoutTemplate, error) {
 	layoutTemplate := make(LayoutTemplate, 0, 10)
 	state := psText
 	startIdx := 0
+	// the format modifier of the placeholder being parsed
+	var format *pieceFormat
 	for i, rune := range layout {
 		switch state {
 		case psText:
 			if rune == '%' {
 				layoutTemplate = addPiece(layout[startIdx:i], lpText, layoutTemplate)
 				state = psPiece
+				startIdx = i + 1
 			}
 		case psPiece:
+			if rune == '-' || rune == '.' || (rune >= '0' && rune <= '9') {
+				break
+			}
+			modifier := layout[startIdx:i]
+			var err error
+			if format, err = parsePieceFormat(modifier); err != nil {
+				return nil, err
+			}
 			state = psText
 			startIdx = i + 1
 			switch rune {
 			case 'c':
-				layoutTemplate = addPiece("c", lpLoggerName, layoutTemplate)
+				state = psLoggerNameStart
 			case 'd':
 				state = psDateStart
 			case 'p':
-				layoutTemplate = addPiece("p", lpLogLevel, layoutTemplate)
+				layoutTemplate = addFormattedPiece("p", lpLogLevel, format, layoutTemplate)
 			case 'm':
-				layoutTemplate = addPiece("m", lpMessage, layoutTemplate)
+				layoutTemplate = addFormattedPiece("m", lpMessage, format, layoutTemplate)
+			case 'X':
+				state = psFieldsStart
+			case 'F':
+				layoutTemplate = addFormattedPiece("F", lpCallerFile, format, layoutTemplate)
+			case 'L':
+				layoutTemplate = addFormattedPiece("L", lpCallerLine, format, layoutTemplate)
+			case 'M':
+				layoutTemplate = addFormattedPiece("M", lpCallerFunction, format, layoutTemplate)
+			case 'J':
+				layoutTemplate = addFormattedPiece("J", lpJSON, format, layoutTemplate)
 			case '%':
+				if modifier != "" {
+					return nil, errors.New("%% cannot have format modifier " + modifier)
+				}
 				startIdx = i
 			default:
 				return nil, errors.New("Unknown layout identifier " + string(rune))
@@ -88,13 +183,73 @@ func ParseLayout(layout string) (LayoutTemplate, error) {
 			state = psDate
 		case psDate:
 			if rune == '}' {
-				layoutTemplate = addPiece(layout[startIdx:i], lpDate, layoutTemplate)
+				layoutTemplate = addFormattedPiece(layout[startIdx:i], lpDate, format, layoutTemplate)
+				state = psText
+				startIdx = i + 1
+			}
+		case psFieldsStart:
+			if rune == '{' {
+				startIdx = i + 1
+				state = psField
+				break
+			}
+			// %X without braces, the rune is a part of the following text
+			layoutTemplate = addFormattedPiece("X", lpFields, format, layoutTemplate)
+			startIdx = i
+			state = psText
+			if rune == '%' {
+				state = psPiece
+				startIdx = i + 1
+			}
+		case psLoggerNameStart:
+			if rune == '{' {
+				startIdx = i + 1
+				state = psLoggerName
+				break
+			}
+			// %c without braces, the rune is a part of the following text
+			layoutTemplate = addFormattedPiece("c", lpLoggerName, format, layoutTemplate)
+			startIdx = i
+			state = psText
+			if rune == '%' {
+				state = psPiece
+				startIdx = i + 1
+			}
+		case psLoggerName:
+			if rune == '}' {
+				lnf, err := parseLoggerNameFormat(layout[startIdx:i])
+				if err != nil {
+					return nil, err
+				}
+				layoutTemplate = addFormattedPiece("c", lpLoggerName, format, layoutTemplate)
+				layoutTemplate[len(layoutTemplate)-1].name = lnf
+				state = psText
+				startIdx = i + 1
+			}
+		case psField:
+			if rune == '}' {
+				if startIdx == i {
+					return nil, errors.New("%X{} should contain a field key in braces like this: %X{key}")
+				}
+				layoutTemplate = addFormattedPiece(layout[startIdx:i], lpField, format, layoutTemplate)
 				state = psText
 				startIdx = i + 1
 			}
 		}
 	}
 
+	if state == psFieldsStart {
+		layoutTemplate = addFormattedPiece("X", lpFields, format, layoutTemplate)
+		state = psText
+		startIdx = len(layout)
+	}
+
+	if state == psLoggerNameStart {
+		layoutTemplate = addFormattedPiece("c", lpLoggerName, format, layoutTemplate)
+		state = psText
+		startIdx = len(layout)
+	}
+
 	if state != psText {
 		return nil, errors.New("Unexpected end of layout, cannot parse it properly")
 	}
@@ -115,17 +270,49 @@ func ToLogMessage(logEvent *LogEvent, template LayoutTemplate) string {
 	buf := bytes.NewBuffer(make([]byte, 0, 64))
 
 	for _, piece := range template {
+		start := buf.Len()
 		switch piece.pieceType {
 		case lpText:
 			buf.WriteString(piece.value)
 		case lpLoggerName:
-			buf.WriteString(logEvent.LoggerName)
+			if piece.name != nil {
+				piece.name.write(buf, logEvent.LoggerName)
+			} else {
+				buf.WriteString(logEvent.LoggerName)
+			}
 		case lpDate:
 			buf.WriteString(logEvent.Timestamp.Format(piece.value))
 		case lpLogLevel:
-			buf.WriteString(logLevelNames[logEvent.Level])
+			buf.WriteString((*logLevelNames.Load())[logEvent.Level])
 		case lpMessage:
 			buf.WriteString(fmt.Sprint(logEvent.Payload))
+		case lpFields:
+			writeFields(buf, logEvent.Fields)
+		case lpField:
+			writeFieldValue(buf, logEvent.Fields, piece.value)
+		case lpCallerFile:
+			if logEvent.Caller != nil {
+				buf.WriteString(filepath.Base(logEvent.Caller.File))
+			}
+		case lpCallerLine:
+			if logEvent.Caller != nil {
+				buf.WriteString(strconv.Itoa(logEvent.Caller.Line))
+			}
+		case lpCallerFunction:
+			if logEvent.Caller != nil {
+				buf.WriteString(logEvent.Caller.Function)
+			}
+		case lpJSON:
+			jl := piece.json
+			if jl == nil {
+				jl = defaultJSONLayout
+			}
+			jl.write(buf, logEvent)
+		case lpLogfmt:
+			writeLogfmt(buf, logEvent)
+		}
+		if piece.format != nil {
+			piece.format.apply(buf, start)
 		}
 	}
 	return buf.String()
@@ -135,5 +322,136 @@ func addPiece(str string, pieceType int, template LayoutTemplate) LayoutTemplate
 	if len(str) == 0 {
 		return template
 	}
-	return append(template, layoutPiece{str, pieceType})
+	return append(template, layoutPiece{value: str, pieceType: pieceType})
+}
+
+func addFormattedPiece(str string, pieceType int, format *pieceFormat, template LayoutTemplate) LayoutTemplate {
+	template = addPiece(str, pieceType, template)
+	template[len(template)-1].format = format
+	return template
+}
+
+// parses the format modifier like "-20.30", returns nil if the modifier is empty
+func parsePieceFormat(modifier string) (*pieceFormat, error) {
+	if modifier == "" {
+		return nil, nil
+	}
+	pf := &pieceFormat{}
+	var err error
+	width, maxWidth, hasMax := strings.Cut(modifier, ".")
+	if width, pf.leftAlign = strings.CutPrefix(width, "-"); width != "" {
+		if pf.minWidth, err = strconv.Atoi(width); err != nil || pf.minWidth < 0 {
+			return nil, errors.New("Incorrect minimum width in format modifier " + modifier)
+		}
+	}
+	if hasMax {
+		maxWidth, pf.truncateEnd = strings.CutPrefix(maxWidth, "-")
+		if pf.maxWidth, err = strconv.Atoi(maxWidth); err != nil || pf.maxWidth <= 0 {
+			return nil, errors.New("Incorrect maximum width in format modifier " + modifier +
+				", it should be positive number")
+		}
+	}
+	return pf, nil
+}
+
+// parses the logger name abbreviation like "2" or "1."
+func parseLoggerNameFormat(spec string) (*loggerNameFormat, error) {
+	num, abbreviate := strings.CutSuffix(spec, ".")
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return nil, errors.New("%c{" + spec + "} should contain positive number of the name components " +
+			"like %c{2}, or of the ancestor components letters like %c{1.}")
+	}
+	if abbreviate {
+		return &loggerNameFormat{ancestorLen: n}, nil
+	}
+	return &loggerNameFormat{components: n}, nil
+}
+
+// writes the logger name split on '.' like the loggers hierarchy is
+func (lnf *loggerNameFormat) write(buf *bytes.Buffer, loggerName string) {
+	if lnf.components > 0 {
+		idx := len(loggerName)
+		for n := lnf.components; n > 0 && idx >= 0; n-- {
+			idx = strings.LastIndexByte(loggerName[:idx], '.')
+		}
+		buf.WriteString(loggerName[idx+1:])
+		return
+	}
+
+	for {
+		idx := strings.IndexByte(loggerName, '.')
+		if idx < 0 {
+			buf.WriteString(loggerName)
+			return
+		}
+		component, n := loggerName[:idx], 0
+		for i := range component {
+			if n == lnf.ancestorLen {
+				component = component[:i]
+				break
+			}
+			n++
+		}
+		buf.WriteString(component)
+		buf.WriteByte('.')
+		loggerName = loggerName[idx+1:]
+	}
+}
+
+// truncates and pads the piece value written to buf from start position
+func (pf *pieceFormat) apply(buf *bytes.Buffer, start int) {
+	value := buf.Bytes()[start:]
+	length := utf8.RuneCount(value)
+	if length >= pf.minWidth && (pf.maxWidth == 0 || length <= pf.maxWidth) {
+		return
+	}
+
+	str := string(value)
+	if pf.maxWidth > 0 && length > pf.maxWidth {
+		runes := []rune(str)
+		if pf.truncateEnd {
+			runes = runes[:pf.maxWidth]
+		} else {
+			runes = runes[length-pf.maxWidth:]
+		}
+		str = string(runes)
+		length = pf.maxWidth
+	}
+
+	buf.Truncate(start)
+	padding := ""
+	if length < pf.minWidth {
+		padding = strings.Repeat(" ", pf.minWidth-length)
+	}
+	if pf.leftAlign {
+		buf.WriteString(str)
+		buf.WriteString(padding)
+	} else {
+		buf.WriteString(padding)
+		buf.WriteString(str)
+	}
+}
+
+// writes all fields in the form key1=value1 key2=value2 ...
+func writeFields(buf *bytes.Buffer, fields []Field) {
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(fmt.Sprint(f.Value))
+	}
+}
+
+// writes value of the last field with the key, so a field added later
+// overrides the one with the same key bound before
+func writeFieldValue(buf *bytes.Buffer, fields []Field, key string) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			buf.WriteString(fmt.Sprint(fields[i].Value))
+			return
+		}
+	}
 }
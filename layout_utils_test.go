@@ -34,6 +34,14 @@ func (s *layoutUtilsSuite) TestParseLayoutFail(c *C) {
 	t, err = ParseLayout("%")
 	c.Assert(t, IsNil)
 	c.Assert(err, NotNil)
+
+	t, err = ParseLayout("%X{}")
+	c.Assert(t, IsNil)
+	c.Assert(err, NotNil)
+
+	t, err = ParseLayout("%X{abc")
+	c.Assert(t, IsNil)
+	c.Assert(err, NotNil)
 }
 
 func (s *layoutUtilsSuite) TestParseLayout(c *C) {
@@ -62,6 +70,91 @@ func (s *layoutUtilsSuite) TestParseLayout(c *C) {
 
 func (s *layoutUtilsSuite) TestToLogMessage(c *C) {
 	t, _ := ParseLayout("[%d{01-02 15:04:05.000}] %p %c: %%%m")
-	le := &LogEvent{FATAL, time.Unix(123456, 0), "a.b.c", "The Message"}
+	le := &LogEvent{Level: FATAL, Timestamp: time.Unix(123456, 0), LoggerName: "a.b.c", Payload: "The Message"}
 	c.Assert(ToLogMessage(le, t), Equals, "[01-02 02:17:36.000] FATAL a.b.c: %The Message")
 }
+
+func (s *layoutUtilsSuite) TestParseLayoutFields(c *C) {
+	t, _ := ParseLayout("%X")
+	c.Assert(len(t), Equals, 1)
+	c.Assert(t[0].pieceType, Equals, lpFields)
+
+	t, _ = ParseLayout("%m %X%%%X{user}:%X")
+	c.Assert(len(t), Equals, 7)
+	c.Assert(t[0].pieceType, Equals, lpMessage)
+	c.Assert(t[1].pieceType, Equals, lpText)
+	c.Assert(t[2].pieceType, Equals, lpFields)
+	c.Assert(t[3].value, Equals, "%")
+	c.Assert(t[3].pieceType, Equals, lpText)
+	c.Assert(t[4].value, Equals, "user")
+	c.Assert(t[4].pieceType, Equals, lpField)
+	c.Assert(t[5].value, Equals, ":")
+	c.Assert(t[6].pieceType, Equals, lpFields)
+}
+
+func (s *layoutUtilsSuite) TestToLogMessageFields(c *C) {
+	t, _ := ParseLayout("%m [%X] user=%X{user} unknown=%X{unknown}")
+	le := &LogEvent{Level: INFO, LoggerName: "a", Payload: "The Message",
+		Fields: []Field{{"user", "john"}, {"latency", 12}, {"user", "bob"}}}
+	c.Assert(ToLogMessage(le, t), Equals, "The Message [user=john latency=12 user=bob] user=bob unknown=")
+
+	le.Fields = nil
+	c.Assert(ToLogMessage(le, t), Equals, "The Message [] user= unknown=")
+}
+
+func (s *layoutUtilsSuite) TestFormatModifiers(c *C) {
+	le := &LogEvent{Level: INFO, Timestamp: time.Unix(123456, 0).UTC(), LoggerName: "a.b.c", Payload: "The Message",
+		Fields: []Field{{"user", "john"}}}
+	layouts := map[string]string{
+		"%-5p|%5p|%p":          "INFO | INFO|INFO",
+		"%-8c|%8c|%.3c|%.-3c":  "a.b.c   |   a.b.c|b.c|a.b",
+		"%-7.7m|%.-40m|%2.4m":  "Message|The Message|sage",
+		"%5X{user}|%-10X|%.3X": " john|user=john |ohn",
+		"%10d{15:04}|%-8.-4J":  "     10:17|{\"ti    ",
+		"%5F|%-3L|":            "     |   |",
+	}
+	for layout, msg := range layouts {
+		t, err := ParseLayout(layout)
+		c.Assert(err, IsNil, Commentf("%s", layout))
+		c.Assert(ToLogMessage(le, t), Equals, msg, Commentf("%s", layout))
+	}
+
+	le.Payload = "Привет, мир"
+	t, _ := ParseLayout("%.3m|%-5.-6m|")
+	c.Assert(ToLogMessage(le, t), Equals, "мир|Привет|")
+
+	for _, layout := range []string{"%-", "%5", "%5%", "%5.p", "%.0p", "%5-p", "%--5p", "%.-p", "%1.2.3p"} {
+		_, err := ParseLayout(layout)
+		c.Assert(err, NotNil, Commentf("%s", layout))
+	}
+}
+
+func (s *layoutUtilsSuite) TestLoggerNameAbbreviation(c *C) {
+	le := &LogEvent{Level: INFO, LoggerName: "payments.gateway.adapters.stripe.client", Payload: "m"}
+	layouts := map[string]string{
+		"%c{1}":           "client",
+		"%c{2}:%c":        "stripe.client:payments.gateway.adapters.stripe.client",
+		"%c{5}|%c{10}":    "payments.gateway.adapters.stripe.client|payments.gateway.adapters.stripe.client",
+		"%c{1.}":          "p.g.a.s.client",
+		"%c{3.} %m":       "pay.gat.ada.str.client m",
+		"%-12c{1}|%.5c%m": "client      |lientm",
+		"%c%%":            "payments.gateway.adapters.stripe.client%",
+	}
+	for layout, msg := range layouts {
+		t, err := ParseLayout(layout)
+		c.Assert(err, IsNil, Commentf("%s", layout))
+		c.Assert(ToLogMessage(le, t), Equals, msg, Commentf("%s", layout))
+	}
+
+	t, _ := ParseLayout("[%c{1.}|%c{2}]")
+	for name, msg := range map[string]string{"": "[|]", "client": "[client|client]", "ш.щ.э": "[ш.щ.э|щ.э]",
+		"a..b": "[a..b|.b]"} {
+		le.LoggerName = name
+		c.Assert(ToLogMessage(le, t), Equals, msg)
+	}
+
+	for _, layout := range []string{"%c{}", "%c{0}", "%c{-1}", "%c{x}", "%c{.}", "%c{1..}", "%c{1"} {
+		_, err := ParseLayout(layout)
+		c.Assert(err, NotNil, Commentf("%s", layout))
+	}
+}
@@ -0,0 +1,140 @@
+package log4g
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// levelOverride is the level which is set for the logger name temporarily.
+// When the duration is over, the setting which was before the override is
+// restored
+type levelOverride struct {
+	loggerName string
+	level      Level
+	duration   time.Duration
+	// the level setting before the override, the level is inherited from an
+	// ancestor if prevExplicit is false
+	prevLevel    Level
+	prevExplicit bool
+	timer        *time.Timer
+	// when the override is reverted
+	expires time.Time
+}
+
+// newLevelOverride returns the override of the logger level set by the config
+// with the duration specified by levelDuration attribute
+func (lc *logConfig) newLevelOverride(loggerName string, level Level, durStr string) *levelOverride {
+	d, err := time.ParseDuration(strings.Trim(durStr, " "))
+	if err != nil || d <= 0 {
+		panic("Incorrect logger attribute " + cfgLoggerLevelDuration + " value \"" + durStr + "\" for logger \"" +
+			loggerName + "\", expected positive duration like 15m")
+	}
+	lo := &levelOverride{loggerName: loggerName, level: level, duration: d}
+	lo.prevLevel, lo.prevExplicit = lc.getLogLevel(loggerName)
+	return lo
+}
+
+// returns the level name or the level number if the level has no name
+func (lc *logConfig) levelName(level Level) string {
+	if level >= 0 && int(level) < len(lc.levelNames) {
+		if name := lc.levelNames[level]; name != "" {
+			return name
+		}
+	}
+	return strconv.Itoa(int(level))
+}
+
+// infoEventSender returns the function which sends INFO event with the msg
+// to the context of the logger name. The event is not filtered by the logger
+// level. The function should be called when the logManager lock is released
+func (lc *logConfig) infoEventSender(loggerName, msg string) func() {
+	lctx := getLogLevelContext(loggerName, lc.logContexts)
+	le := &LogEvent{Level: INFO, Timestamp: time.Now(), LoggerName: loggerName, Payload: msg}
+	return func() {
+		if lctx != nil {
+			lctx.log(le)
+		}
+	}
+}
+
+func (lm *logManager) setLogLevelFor(loggerName string, level Level, d time.Duration) error {
+	if level < 0 || level > ALL {
+		return errors.New("Cannot set log level " + strconv.Itoa(int(level)) + ": the level should be in [0.." +
+			strconv.Itoa(int(ALL)) + "]")
+	}
+	if d <= 0 {
+		return errors.New("Cannot set log level for non-positive duration " + d.String())
+	}
+	loggerName = normalizeLogName(loggerName)
+
+	lm.rwLock.Lock()
+	lm.config.initIfNeeded()
+	lo := &levelOverride{loggerName: loggerName, level: level, duration: d}
+	lo.prevLevel, lo.prevExplicit = lm.config.getLogLevel(loggerName)
+	lm.config.setLogLevel(level, loggerName)
+	send := lm.startOverride(lo)
+	lm.rwLock.Unlock()
+
+	send()
+	return nil
+}
+
+// startOverride schedules the revert of the override level, which is already
+// applied to the config. If there is an override for the logger name, it is
+// replaced, but the setting before the first override is restored anyway.
+// Must be called under the lock, returns the sender of the event about the override
+func (lm *logManager) startOverride(lo *levelOverride) func() {
+	if lm.overrides == nil {
+		lm.overrides = make(map[string]*levelOverride)
+	}
+	if prev, ok := lm.overrides[lo.loggerName]; ok {
+		prev.timer.Stop()
+		lo.prevLevel, lo.prevExplicit = prev.prevLevel, prev.prevExplicit
+	}
+	lm.overrides[lo.loggerName] = lo
+	lo.expires = time.Now().Add(lo.duration)
+	lo.timer = time.AfterFunc(lo.duration, func() { lm.revertLogLevel(lo) })
+
+	return lm.config.infoEventSender(lo.loggerName, "Log level of logger \""+lo.loggerName+"\" is set to "+
+		lm.config.levelName(lo.level)+" for "+lo.duration.String())
+}
+
+// revertLogLevel restores the level setting which was before the override,
+// unless the override is replaced or cancelled
+func (lm *logManager) revertLogLevel(lo *levelOverride) {
+	lm.rwLock.Lock()
+	if lm.overrides[lo.loggerName] != lo {
+		lm.rwLock.Unlock()
+		return
+	}
+	delete(lm.overrides, lo.loggerName)
+	if lo.prevExplicit {
+		lm.config.setLogLevel(lo.prevLevel, lo.loggerName)
+	} else {
+		lm.config.resetLogLevel(lo.loggerName)
+	}
+	level, _ := lm.config.getLogLevel(lo.loggerName)
+	send := lm.config.infoEventSender(lo.loggerName, "Log level of logger \""+lo.loggerName+"\" is reverted to "+
+		lm.config.levelName(level))
+	lm.rwLock.Unlock()
+
+	send()
+}
+
+// cancelOverride stops the override for the logger name, so its current
+// level is kept. Must be called under the lock
+func (lm *logManager) cancelOverride(loggerName string) {
+	if lo, ok := lm.overrides[loggerName]; ok {
+		lo.timer.Stop()
+		delete(lm.overrides, loggerName)
+	}
+}
+
+// cancelOverrides stops all the overrides. Must be called under the lock
+func (lm *logManager) cancelOverrides() {
+	for name := range lm.overrides {
+		lm.cancelOverride(name)
+	}
+}
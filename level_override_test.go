@@ -0,0 +1,141 @@
+package log4g
+
+import (
+	"context"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"sync"
+	"time"
+)
+
+type levelOverrideSuite struct {
+}
+
+var _ = Suite(&levelOverrideSuite{})
+
+const collectingAppenderName = "test/collectingAppender"
+
+// collectingAppender keeps the payloads of the appended events
+type collectingAppender struct {
+	testAppender
+	lock     sync.Mutex
+	payloads []string
+}
+
+type collectingAppenderFactory struct {
+	app *collectingAppender
+}
+
+func (ca *collectingAppender) Append(event *LogEvent) bool {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	ca.payloads = append(ca.payloads, fmt.Sprint(event.Payload))
+	return true
+}
+
+func (ca *collectingAppender) getPayloads() []string {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	return append([]string{}, ca.payloads...)
+}
+
+func (caf *collectingAppenderFactory) Name() string {
+	return collectingAppenderName
+}
+
+func (caf *collectingAppenderFactory) NewAppender(params map[string]string) (Appender, error) {
+	return caf.app, nil
+}
+
+func (caf *collectingAppenderFactory) Shutdown() {
+}
+
+func newOverrideTestLogManager(c *C, props map[string]string) (*logManager, *collectingAppender) {
+	m := newTestLogManager(c)
+	app := &collectingAppender{}
+	c.Assert(m.registerAppender(&collectingAppenderFactory{app}), IsNil)
+	props["appender.ROOT.type"] = collectingAppenderName
+	props["context.appenders"] = "ROOT"
+	c.Assert(m.setNewProperties(props), IsNil)
+	return m, app
+}
+
+func checkLogLevel(c *C, m *logManager, loggerName string, level Level, explicit bool) {
+	l, e := m.getLogLevel(loggerName)
+	c.Assert(l, Equals, level)
+	c.Assert(e, Equals, explicit)
+}
+
+func (s *levelOverrideSuite) TestSetLogLevelFor(c *C) {
+	m, app := newOverrideTestLogManager(c, map[string]string{"logger.a.level": "WARN"})
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.setLogLevelFor("a.b", TRACE, 50*time.Millisecond), IsNil)
+	checkLogLevel(c, m, "a.b", TRACE, true)
+	c.Assert(m.getLogger("a.b.c").(*logger).getState().logLevel, Equals, TRACE)
+
+	time.Sleep(100 * time.Millisecond)
+	checkLogLevel(c, m, "a.b", WARN, false)
+	c.Assert(m.getLogger("a.b.c").(*logger).getState().logLevel, Equals, WARN)
+
+	c.Assert(m.flush(context.Background()), IsNil)
+	c.Assert(app.getPayloads(), DeepEquals, []string{"Log level of logger \"a.b\" is set to TRACE for 50ms",
+		"Log level of logger \"a.b\" is reverted to WARN"})
+
+	c.Assert(m.setLogLevelFor("a", TRACE, 0), NotNil)
+	c.Assert(m.setLogLevelFor("a", -1, time.Second), NotNil)
+}
+
+func (s *levelOverrideSuite) TestOverlappingOverrides(c *C) {
+	m, _ := newOverrideTestLogManager(c, map[string]string{"logger.a.level": "WARN"})
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.setLogLevelFor("a", DEBUG, 50*time.Millisecond), IsNil)
+	c.Assert(m.setLogLevelFor("a", TRACE, 200*time.Millisecond), IsNil)
+	time.Sleep(100 * time.Millisecond)
+	// the first override doesn't revert the second one
+	checkLogLevel(c, m, "a", TRACE, true)
+	time.Sleep(200 * time.Millisecond)
+	// the setting before the first override is restored
+	checkLogLevel(c, m, "a", WARN, true)
+
+	// an explicit level cancels the override
+	c.Assert(m.setLogLevelFor("a", DEBUG, 50*time.Millisecond), IsNil)
+	m.setLogLevel("a", ERROR)
+	time.Sleep(100 * time.Millisecond)
+	checkLogLevel(c, m, "a", ERROR, true)
+}
+
+func (s *levelOverrideSuite) TestOverrideAndConfig(c *C) {
+	m, _ := newOverrideTestLogManager(c, map[string]string{})
+	defer m.shutdown(context.Background())
+
+	c.Assert(m.setLogLevelFor("a", TRACE, 50*time.Millisecond), IsNil)
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": collectingAppenderName,
+		"context.appenders": "ROOT", "logger.a.level": "WARN"}), IsNil)
+	checkLogLevel(c, m, "a", WARN, true)
+	time.Sleep(100 * time.Millisecond)
+	// the expired override doesn't revert the level set by the config
+	checkLogLevel(c, m, "a", WARN, true)
+
+	c.Assert(m.setLogLevelFor("b", TRACE, 50*time.Millisecond), IsNil)
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": collectingAppenderName,
+		"context.appenders": "ROOT"}), IsNil)
+	time.Sleep(100 * time.Millisecond)
+	checkLogLevel(c, m, "b", INFO, false)
+}
+
+func (s *levelOverrideSuite) TestConfigLevelDuration(c *C) {
+	m, _ := newOverrideTestLogManager(c, map[string]string{"context.level": "WARN",
+		"logger.a.level": "DEBUG", "logger.a.levelDuration": "50ms"})
+	defer m.shutdown(context.Background())
+
+	checkLogLevel(c, m, "a", DEBUG, true)
+	time.Sleep(100 * time.Millisecond)
+	checkLogLevel(c, m, "a", WARN, false)
+
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": collectingAppenderName,
+		"context.appenders": "ROOT", "logger.a.levelDuration": "1m"}), NotNil)
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": collectingAppenderName,
+		"context.appenders": "ROOT", "logger.a.level": "DEBUG", "logger.a.levelDuration": "-1m"}), NotNil)
+}
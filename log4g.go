@@ -1,6 +1,11 @@
 package log4g
 
-import "time"
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+)
 
 // Level type represents logging level as an integer value which lies in [0..70] range.
 // A level with lowest value has higher priority than a level with highest value.
@@ -32,6 +37,48 @@ type Logger interface {
 	Log(level Level, args ...interface{})
 	Logf(level Level, fstr string, args ...interface{})
 	Logp(level Level, payload interface{})
+
+	// The methods with 'w' suffix send the message together with structured
+	// fields. The fields are provided as key-value pairs, like
+	// Infow("request processed", "requestId", id, "latency", d)
+	Fatalw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Debugw(msg string, keysAndValues ...interface{})
+	Tracew(msg string, keysAndValues ...interface{})
+	Logw(level Level, msg string, keysAndValues ...interface{})
+
+	// With returns a child logger which adds the provided key-value pairs
+	// to every log event it emits. The child logger shares the level
+	// settings with its parent.
+	With(keysAndValues ...interface{}) Logger
+
+	// The methods with 'Ctx' suffix do the same as their counterparts, but
+	// add the diagnostic fields, attached to ctx by ContextWith(), to the
+	// log event
+	FatalCtx(ctx context.Context, args ...interface{})
+	ErrorCtx(ctx context.Context, args ...interface{})
+	WarnCtx(ctx context.Context, args ...interface{})
+	InfoCtx(ctx context.Context, args ...interface{})
+	DebugCtx(ctx context.Context, args ...interface{})
+	TraceCtx(ctx context.Context, args ...interface{})
+	LogCtx(ctx context.Context, level Level, args ...interface{})
+	LogfCtx(ctx context.Context, level Level, fstr string, args ...interface{})
+	LogwCtx(ctx context.Context, level Level, msg string, keysAndValues ...interface{})
+
+	// WithCallerSkip returns a child logger which skips additional frames
+	// when the caller location is captured. It allows wrappers around the
+	// Logger to report location of their callers instead of their own one.
+	WithCallerSkip(skip int) Logger
+}
+
+// Field is a key-value pair which bears a structured value of a log event.
+// Fields are kept in the order they were provided, so the layouts can render
+// them in a stable way
+type Field struct {
+	Key   string
+	Value interface{}
 }
 
 // LogEvent is DTO, bearing a log message between the log4g components. This
@@ -43,6 +90,17 @@ type LogEvent struct {
 	Timestamp  time.Time
 	LoggerName string
 	Payload    interface{}
+	Fields     []Field
+	// Caller is the source code location the event was emitted from. It is
+	// nil unless caller capturing is enabled for the logger or its context
+	Caller *CallerInfo
+}
+
+// CallerInfo describes the source code location of a logging call
+type CallerInfo struct {
+	File     string
+	Line     int
+	Function string
 }
 
 // Appender is an interface for a log endpoint. Different log storages can be
@@ -53,6 +111,125 @@ type Appender interface {
 	Shutdown()
 }
 
+// Flusher is an optional interface which can be implemented by an Appender
+// which writes log events asynchronously. Flush should return when all events
+// appended before the call are written to the final destination, or when ctx
+// is done. In the last case ctx.Err() is returned.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// ErrorHandler is called when an appender fails to deliver the event. The
+// event is nil if the error is not related to an event, e.g. the file
+// appender cannot reopen its file. It can be called from different go
+// routines concurrently
+type ErrorHandler func(event *LogEvent, err error)
+
+// ErrorReporter is an optional interface which can be implemented by an
+// Appender which delivers events asynchronously, so it cannot return the error
+// from Append(). log4g calls SetErrorHandler() right after the appender is
+// created, and the appender should call the handler for every failed event
+type ErrorReporter interface {
+	SetErrorHandler(handler ErrorHandler)
+}
+
+// ParamsChecker is an optional interface which can be implemented by an
+// AppenderFactory to let Validate() check the appender params without
+// creating the appender
+type ParamsChecker interface {
+	// Params returns names of all the params supported by the factory appenders
+	Params() []string
+
+	// CheckParams returns errors of the params by the param names, the errors
+	// which are not related to a particular param are returned with "" name.
+	// It must not create the appender or touch any resources
+	CheckParams(params map[string]string) map[string]error
+}
+
+// ConfigDecoder reads a config document in the form encoding/json decodes
+// JSON documents to: nested map[string]interface{} and []interface{} with
+// scalar values. The scalars can be strings, json.Number, bool or nil.
+type ConfigDecoder func(r io.Reader) (interface{}, error)
+
+// ConfigError describes a problem of log4g configuration found by Validate()
+type ConfigError struct {
+	// the property key the problem is related to
+	Key string
+	// the file and the line where the property is defined, if it is known
+	File string
+	Line int
+	Msg  string
+}
+
+func (ce ConfigError) Error() string {
+	var prefix string
+	if ce.File != "" {
+		prefix = ce.File + ":"
+		if ce.Line > 0 {
+			prefix += strconv.Itoa(ce.Line) + ":"
+		}
+		prefix += " "
+	}
+	if ce.Key != "" {
+		prefix += ce.Key + ": "
+	}
+	return prefix + ce.Msg
+}
+
+// StatsReporter is an optional interface which can be implemented by an
+// Appender to provide its specific runtime statistics, like number of bytes
+// written. The values are reported by Stats() in AppenderStats.Details
+type StatsReporter interface {
+	Stats() map[string]int64
+}
+
+// Statistics is a snapshot of log4g runtime statistics returned by Stats()
+type Statistics struct {
+	Contexts  []ContextStats
+	Appenders []AppenderStats
+}
+
+// ContextStats contains runtime statistics of a logger context
+type ContextStats struct {
+	Name string
+	// number of events in the context buffer and the buffer size
+	QueueDepth int
+	Capacity   int
+	Enqueued   uint64
+	Dropped    uint64
+	Processed  uint64
+	// total and average time of delivering an event to the context appenders
+	ProcessingTime    time.Duration
+	AvgProcessingTime time.Duration
+}
+
+// AppenderStats contains runtime statistics of an appender
+type AppenderStats struct {
+	Name    string
+	Appends uint64
+	// number of Append() calls which returned false
+	Errors uint64
+	// total time spent in Append() calls
+	AppendTime time.Duration
+	// appender specific values provided by StatsReporter
+	Details map[string]int64
+}
+
+// LoggerInfo describes the settings a logger works with, it is returned by Loggers()
+type LoggerInfo struct {
+	Name string
+	// the effective level of the logger and its name
+	Level     Level
+	LevelName string
+	// the name of the logger which level setting is inherited, it is equal
+	// to Name if the level is set for the logger explicitly
+	LevelSetting string
+	// the logger context name and the names of the appenders the events are
+	// delivered to, including the ones of the inherited contexts
+	Context   string
+	Appenders []string
+}
+
 // The factory allows to create an appender instances
 type AppenderFactory interface {
 	// Appender name
@@ -61,6 +238,21 @@ type AppenderFactory interface {
 	Shutdown()
 }
 
+// ContextWith returns a copy of ctx which bears the key-value pair as a
+// mapped diagnostic context (MDC) field. The fields attached to the context
+// are added to every log event emitted by the Logger methods with 'Ctx'
+// suffix, so a value (like trace id) set once can be seen in all log lines
+// made with the context. The fields attached before are kept.
+func ContextWith(ctx context.Context, key string, value interface{}) context.Context {
+	return contextWith(ctx, key, value)
+}
+
+// ContextFields returns the diagnostic fields attached to ctx by ContextWith()
+// in the order they were attached, or nil if there are no such fields.
+func ContextFields(ctx context.Context) []Field {
+	return contextFields(ctx)
+}
+
 // SetLogLevelName allows to associate level with its name. All messages with
 // the level, which have been emitted after this settings, will appear with the
 // provided name.
@@ -70,6 +262,13 @@ func SetLogLevelName(level Level, name string) bool {
 	return lm.setLogLevelName(int(level), name)
 }
 
+// GetLevelByName returns the level associated with the name. The name is
+// case-insensitive and its leading and trailing spaces are ignored. Returns
+// false if there is no level with the name.
+func GetLevelByName(levelName string) (Level, bool) {
+	return lm.getLevelByName(levelName)
+}
+
 // GetLogger returns pointer to the Logger object for specified logger name.
 // The function will always return the same pointer for the same logger's name
 // regardless of log4g configuration or other settings
@@ -114,6 +313,35 @@ func SetLogLevel(loggerName string, level Level) {
 	lm.setLogLevel(loggerName, level)
 }
 
+// SetLogLevelFor sets the level for the logger name like SetLogLevel() does,
+// but only for the duration. When the duration is over, the level setting
+// which was before the call is restored: the level set explicitly, or the
+// level inherited from an ancestor. Calling the function for the same name
+// again replaces the level and the duration, but the setting before the first
+// call is restored anyway. SetLogLevel() or ResetLogLevel() calls for the name
+// cancel the revert. INFO events are logged for the logger name when the
+// level is set and reverted.
+func SetLogLevelFor(loggerName string, level Level, duration time.Duration) error {
+	return lm.setLogLevelFor(loggerName, level, duration)
+}
+
+// GetLogLevel returns the effective level of the logger name and whether the
+// level is set for the name explicitly by SetLogLevel() or configuration. If
+// it is not, the level is inherited from the nearest ancestor in the logger
+// name tree.
+func GetLogLevel(loggerName string) (level Level, explicit bool) {
+	return lm.getLogLevel(loggerName)
+}
+
+// ResetLogLevel removes the level set for the logger name explicitly, so the
+// logger and its descendants, which have no own level settings, inherit the
+// level from the nearest ancestor in the logger name tree. The level of the
+// root logger "" cannot be removed. Returns false if there is no explicit
+// level for the name.
+func ResetLogLevel(loggerName string) bool {
+	return lm.resetLogLevel(loggerName)
+}
+
 // RegisterAppender allows to register an appender implementation in log4g.
 // All appenders should register themself calling the function from init() or
 // by calling this function directly.
@@ -132,14 +360,172 @@ func ConfigF(configFileName string) error {
 	return lm.setPropsFromFile(configFileName)
 }
 
+// ConfigFile reads log4g configuration from the file. The file format is
+// detected by its extension: .json files are read as JSON documents, the files
+// with the extensions registered by RegisterConfigDecoder() are read by the
+// decoders, and other files are read like ConfigF() does.
+func ConfigFile(configFileName string) error {
+	return lm.setConfigFile(configFileName)
+}
+
+// RegisterConfigDecoder makes ConfigFile(), ValidateFile() and WatchConfigF()
+// read the files with the extensions (like ".yaml") by the decoder. The
+// extensions are case-insensitive, the decoder replaces the one registered
+// for the extension before. The YAML decoder is registered by
+// github.com/dspasibenko/log4g/yamlconfig package.
+func RegisterConfigDecoder(decoder ConfigDecoder, exts ...string) {
+	registerConfigDecoder(decoder, exts)
+}
+
+// ConfigDoc reads log4g configuration from the document decoded by the
+// decoder, the document has the same structure as ConfigJSON() document
+func ConfigDoc(r io.Reader, decoder ConfigDecoder) error {
+	return lm.setConfigReader(r, decoder)
+}
+
+// ConfigJSON reads log4g configuration from JSON document with the following
+// structure, which is mapped to the configuration properties:
+//
+//	{
+//	  "levels":    {"11": "SEVERE"},
+//	  "appenders": {"file": {"type": "log4g/fileAppender", "fileName": "app.log", "layout": "%p %m"}},
+//	  "contexts":  {"": {"appenders": ["file"]}, "a.b": {"level": "DEBUG", "buffer": 1000}},
+//	  "loggers":   {"a.b.c": {"level": "TRACE"}}
+//	}
+//
+// "" stands for the root logger name.
+func ConfigJSON(r io.Reader) error {
+	return lm.setConfigReader(r, parseJSONDoc)
+}
+
+// Validate checks the configuration properties without applying them. It
+// returns all the found problems: unknown keys and attributes, incorrect
+// values, undefined references etc. No appenders are created and no files
+// are touched, appender params are checked only by factories which implement
+// ParamsChecker. nil is returned if no problems are found.
+func Validate(props map[string]string) []ConfigError {
+	return lm.validate(props, nil)
+}
+
+// ValidateFile checks the configuration file like Validate() does. The file
+// format is detected like ConfigFile() does, the problems found in properties
+// files refer to the lines where the properties are defined.
+func ValidateFile(configFileName string) []ConfigError {
+	return lm.validateFile(configFileName)
+}
+
+// WatchConfigF configures log4g from the file like ConfigFile() does, and then
+// checks the file every interval. When the modification time and content of
+// the file, or of a file included by it, are changed, the file is applied again. If the changed file cannot be read
+// or applied, the current configuration is kept and the error is passed to
+// onError, or printed to stderr if onError is nil. Only the last watched file
+// is checked, watching is stopped by Shutdown().
+func WatchConfigF(configFileName string, interval time.Duration, onError func(err error)) error {
+	return lm.watchConfigF(configFileName, interval, onError)
+}
+
 // Config allows to configure log4g by properties provided in the key:value form
 func Config(props map[string]string) error {
 	return lm.setNewProperties(props)
 }
 
+// Flush waits until all log events emitted before the call are delivered to
+// the appenders and written by them. Unlike Shutdown() log4g keeps working
+// after the call.
+func Flush() {
+	lm.flush(context.Background())
+}
+
+// FlushContext does the same as Flush(), but stops waiting when ctx is done.
+// It returns ctx.Err() if not all log events were written by that time.
+func FlushContext(ctx context.Context) error {
+	return lm.flush(ctx)
+}
+
+// SetErrorHandler sets the handler which is called for errors of all the
+// appenders except ones which have their own handler set by
+// SetAppenderErrorHandler(). If no handler is set, the errors are printed to
+// stderr, but not more often than once a minute per appender. nil handler
+// removes the current one.
+func SetErrorHandler(handler ErrorHandler) {
+	setErrorHandler("", handler)
+}
+
+// SetAppenderErrorHandler sets the handler which is called for errors of the
+// appender with the name. The handler is kept when log4g is re-configured.
+// nil handler removes the current one.
+func SetAppenderErrorHandler(appenderName string, handler ErrorHandler) {
+	setErrorHandler(appenderName, handler)
+}
+
+// Stats returns a snapshot of the runtime statistics of all logger contexts
+// and appenders. The same data is published via expvar with "log4g" name.
+func Stats() *Statistics {
+	return lm.stats()
+}
+
+// EffectiveConfig returns the configuration properties which are in force,
+// including the log levels set by SetLogLevel() and the level names set by
+// SetLogLevelName(). The levels set by SetLogLevelFor() have levelDuration
+// attribute with the remaining duration. The properties can be passed to
+// Config() to get the same configuration.
+func EffectiveConfig() map[string]string {
+	return lm.effectiveConfig()
+}
+
+// Loggers returns the settings of all loggers created so far sorted by the
+// logger names.
+func Loggers() []LoggerInfo {
+	return lm.loggersInfo()
+}
+
+// HandleSignals installs handlers of SIGUSR1 and SIGHUP signals. SIGUSR1
+// sets DEBUG level for the logger names, the next one sets TRACE level and
+// the third one restores the levels which were before the first signal, so
+// the cycle starts again. The root logger name "" is used if no names are
+// provided. SIGHUP makes the file appenders close and reopen their files, so
+// logrotate with create mode can move the files without losing lines.
+// Calling the function again replaces the logger names. The signals are not
+// handled after Shutdown(). Returns error if the platform doesn't support
+// the signals.
+func HandleSignals(loggerNames ...string) error {
+	return lm.handleSignals(loggerNames)
+}
+
 // Should be called to shutdown log subsystem properly. It will notify all logContexts and wait
 // while all go routines that deliver messages to appenders are over. Calling this method could
 // be essential to finalize some appenders and release their resources properly
 func Shutdown() {
-	lm.shutdown()
+	lm.shutdown(context.Background())
+}
+
+// ShutdownContext does the same as Shutdown(), but stops waiting when ctx is
+// done. It returns *ShutdownError if some contexts, appenders or appender
+// factories were not finished by that time.
+func ShutdownContext(ctx context.Context) error {
+	return lm.shutdown(ctx)
 }
+
+// ShutdownError is returned by ShutdownContext() when the shutdown is not
+// completed in time. It contains names of the logger contexts, appenders and
+// appender factories which were not finished and the number of log events
+// which were still queued
+type ShutdownError struct {
+	Contexts          []string
+	Appenders         []string
+	Queued            int
+	AppenderFactories []string
+}
+
+func (se *ShutdownError) Error() string {
+	names := "contexts [" + quoteAndJoin(se.Contexts) + "]"
+	appenders := "appenders [" + quoteAndJoin(se.Appenders) + "]"
+	if len(se.AppenderFactories) > 0 {
+		names += ", " + appenders + " and appender factories [" + quoteAndJoin(se.AppenderFactories) + "]"
+	} else {
+		names += " and " + appenders
+	}
+	return "log4g shutdown is not completed: " + names + " are not finished, " +
+		strconv.Itoa(se.Queued) + " events are still queued"
+}
+
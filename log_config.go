@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	"errors"
 	"github.com/dspasibenko/log4g/collections"
 	"strconv"
@@ -11,10 +12,22 @@ type logConfig struct {
 	loggers          map[string]*logger
 	logLevels        *collections.SortedSlice
 	logContexts      *collections.SortedSlice
+	callers          *collections.SortedSlice
 	appenderFactorys map[string]AppenderFactory
 	appenders        map[string]Appender
-	levelNames       []string
-	levelMap         map[string]Level
+	// the attributes the appenders were created with
+	appenderParams map[string]map[string]string
+	// the attributes the contexts were created with
+	contextParams map[string]map[string]string
+	levelNames    []string
+	levelMap      map[string]Level
+	// the logger levels which are set by the config temporarily
+	tempLevels []*levelOverride
+	// the logger names which levels are set by the config permanently
+	configLevels map[string]bool
+	// the config which is replaced by this one, its appenders are reused if
+	// their attributes are not changed. It is set only while the config is initialized
+	prev *logConfig
 }
 
 // Config params
@@ -29,22 +42,38 @@ const (
 	// context.a.b.c.appenders=console,ROOT
 	// context.a.b.c.level=INFO
 	// context.a.b.c.buffer=100
+	// context.a.b.c.caller=true
+	// context.a.b.c.overflow=dropBelowLevel=WARN
 	cfgContext          = "context"
 	cfgContextAppenders = "appenders"
 	cfgContextLevel     = "level"
 	cfgContextBufSize   = "buffer"
 	cfgContextBlocking  = "blocking"
 	cfgContextInherited = "inherited"
+	cfgContextCaller    = "caller"
+	cfgContextOverflow  = "overflow"
 
 	// logger.a.b.c.d.level=INFO
-	cfgLogger      = "logger"
-	cfgLoggerLevel = "level"
+	// logger.a.b.c.d.caller=true
+	// logger.a.b.c.d.levelDuration=15m
+	cfgLogger              = "logger"
+	cfgLoggerLevel         = "level"
+	cfgLoggerCaller        = "caller"
+	cfgLoggerLevelDuration = "levelDuration"
 )
 
+// the maximum size of the context events buffer
+const maxContextBufSize = 100000
+
+// the attributes supported by contexts and loggers
+var contextAttributes = []string{cfgContextAppenders, cfgContextLevel, cfgContextBufSize, cfgContextBlocking,
+	cfgContextInherited, cfgContextCaller, cfgContextOverflow}
+var loggerAttributes = []string{cfgLoggerLevel, cfgLoggerCaller, cfgLoggerLevelDuration}
+
 const rootLoggerName = ""
 
 var defaultConfigParams = map[string]string{
-	"appender.ROOT.layout": "[%d{01-02 15:04:05.000}] %p %c: %m",
+	"appender.ROOT.layout": "[%d{01-02 15:04:05.000}] %-5p %c: %m",
 	"appender.ROOT.type":   consoleAppenderName,
 	"context.appenders":    "ROOT",
 	"context.level":        "INFO"}
@@ -55,18 +84,22 @@ func newLogConfig() *logConfig {
 	lc.loggers = make(map[string]*logger)
 	lc.logLevels, _ = collections.NewSortedSlice(10)
 	lc.logContexts, _ = collections.NewSortedSlice(2)
+	lc.callers, _ = collections.NewSortedSlice(2)
 	lc.appenderFactorys = make(map[string]AppenderFactory)
 	lc.appenders = make(map[string]Appender)
+	lc.appenderParams = make(map[string]map[string]string)
+	lc.contextParams = make(map[string]map[string]string)
 	lc.levelNames = make([]string, ALL+1)
 	lc.levelMap = make(map[string]Level)
+	lc.configLevels = make(map[string]bool)
 
 	lc.levelNames[FATAL] = "FATAL"
 	lc.levelNames[ERROR] = "ERROR"
-	lc.levelNames[WARN] = "WARN "
-	lc.levelNames[INFO] = "INFO "
+	lc.levelNames[WARN] = "WARN"
+	lc.levelNames[INFO] = "INFO"
 	lc.levelNames[DEBUG] = "DEBUG"
 	lc.levelNames[TRACE] = "TRACE"
-	lc.levelNames[ALL] = "ALL  "
+	lc.levelNames[ALL] = "ALL"
 
 	return lc
 }
@@ -91,18 +124,123 @@ func (lc *logConfig) initIfNeeded() {
 		return
 	}
 	lc.setConfigParams(defaultConfigParams)
+	lc.linkFailovers()
 }
 
 func (lc *logConfig) cleanUp() {
-	defer EndQuietly()
+	lc.shutdown(context.Background())
+}
+
+// shutdown stops all contexts, appenders and appender factories of the
+// config. It stops waiting for them when ctx is done and returns
+// *ShutdownError in the case
+func (lc *logConfig) shutdown(ctx context.Context) error {
+	return lc.shutdownExcept(ctx, nil, lc.appenderFactorys)
+}
+
+// replaceBy stops the config which is replaced by newConfig. The events logged
+// to the config contexts after they are stopped are handed over to the
+// newConfig contexts, and the appenders reused by newConfig are not shut down
+func (lc *logConfig) replaceBy(newConfig *logConfig) {
+	for _, c := range lc.logContexts.Copy() {
+		c.(*logContext).successors.Store(newConfig.logContexts)
+	}
+	lc.shutdownExcept(context.Background(), newConfig.appenders, nil)
+}
+
+// shutdownExcept stops the contexts, the appenders except the ones which are
+// in the kept map with the same name, and the factories. All of them are
+// shut down concurrently, but an appender is shut down when the contexts
+// delivering events to it are stopped, and a factory is shut down when the
+// appenders it created are stopped. So only the blocked ones and the ones
+// waiting for them are reported
+func (lc *logConfig) shutdownExcept(ctx context.Context, kept map[string]Appender,
+	factories map[string]AppenderFactory) error {
+	contexts := make(map[string]*logContext)
+	ctxShutdowns := make(map[string]func())
+	for _, c := range lc.logContexts.Copy() {
+		lctx := c.(*logContext)
+		contexts[lctx.loggerName] = lctx
+		ctxShutdowns[lctx.loggerName] = func() { lctx.shutdownContext(context.Background()) }
+	}
+
+	appDone := make(map[string]chan bool)
+	appShutdowns := make(map[string]func())
+	for name, app := range lc.appenders {
+		if kept[name] == app {
+			continue
+		}
+		var users []*logContext
+		for _, lctx := range contexts {
+			if containsAppender(lctx.targets, app) {
+				users = append(users, lctx)
+			}
+		}
+		app, doneCh := app, make(chan bool)
+		appDone[name] = doneCh
+		appShutdowns[name] = func() {
+			defer close(doneCh)
+			for _, lctx := range users {
+				<-lctx.controlCh
+			}
+			app.Shutdown()
+		}
+	}
+
+	factoryShutdowns := make(map[string]func())
+	for name, af := range factories {
+		var created []chan bool
+		for appName, doneCh := range appDone {
+			if lc.appenderParams[appName][cfgAppenderType] == name {
+				created = append(created, doneCh)
+			}
+		}
+		af := af
+		factoryShutdowns[name] = func() {
+			for _, doneCh := range created {
+				<-doneCh
+			}
+			af.Shutdown()
+		}
+	}
+
+	ctxsCh, factoriesCh := make(chan []string, 1), make(chan []string, 1)
+	go func() { ctxsCh <- waitForAll(ctx, ctxShutdowns) }()
+	go func() { factoriesCh <- waitForAll(ctx, factoryShutdowns) }()
+	se := &ShutdownError{Appenders: waitForAll(ctx, appShutdowns), Contexts: <-ctxsCh,
+		AppenderFactories: <-factoriesCh}
+	for _, name := range se.Contexts {
+		se.Queued += len(contexts[name].eventsCh)
+	}
+	for _, name := range se.Appenders {
+		if q, ok := lc.appenders[name].(queuedCounter); ok {
+			se.Queued += q.queued()
+		}
+	}
 
-	for _, ctx := range lc.logContexts.Copy() {
-		ctx.(*logContext).shutdown()
+	if len(se.Contexts) == 0 && len(se.Appenders) == 0 && len(se.AppenderFactories) == 0 {
+		return nil
+	}
+	return se
+}
+
+// flush waits until all contexts pass their events to the appenders and then
+// flushes every appender which implements Flusher
+func (lc *logConfig) flush(ctx context.Context) error {
+	for _, c := range lc.logContexts.Copy() {
+		if err := c.(*logContext).flush(ctx); err != nil {
+			return err
+		}
 	}
 
 	for _, app := range lc.appenders {
-		app.Shutdown()
+		if f, ok := app.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 func (lc *logConfig) initWithParams(oldLogConfig *logConfig, params map[string]string) {
@@ -114,8 +252,11 @@ func (lc *logConfig) initWithParams(oldLogConfig *logConfig, params map[string]s
 		lc.appenderFactorys[k] = v
 	}
 
-	lc.levelNames = oldLogConfig.levelNames
+	copy(lc.levelNames, oldLogConfig.levelNames)
 	lc.logLevels, _ = collections.NewSortedSliceByParams(oldLogConfig.logLevels.Copy()...)
+	lc.callers, _ = collections.NewSortedSliceByParams(oldLogConfig.callers.Copy()...)
+	lc.prev = oldLogConfig
+	defer func() { lc.prev = nil }()
 	lc.setConfigParams(params)
 }
 
@@ -159,6 +300,12 @@ func (lc *logConfig) createAppenders(params map[string]string) {
 
 	// create appenders
 	for appName, appAttributes := range apps {
+		lc.appenderParams[appName] = appAttributes
+		if app := lc.getReusableAppender(appName, appAttributes); app != nil {
+			lc.appenders[appName] = app
+			continue
+		}
+
 		t := appAttributes[cfgAppenderType]
 		f, ok := lc.appenderFactorys[t]
 		if !ok {
@@ -170,16 +317,58 @@ func (lc *logConfig) createAppenders(params map[string]string) {
 			panic(err.Error())
 		}
 
-		lc.appenders[appName] = app
+		lc.appenders[appName] = newStatAppender(appName, app)
+	}
+
+	// resolve references between appenders when all of them are created
+	for appName := range apps {
+		if l, ok := lc.appenders[appName].(*statAppender).Appender.(appenderLinker); ok {
+			if err := l.linkAppenders(lc.appenders); err != nil {
+				panic(err.Error())
+			}
+		}
+	}
+}
+
+// linkFailovers makes the failover appenders of the config handle the errors
+// of their primaries. The reused appenders are not bound to the failover
+// appenders of the replaced config anymore. It must be called when the config
+// is installed only, so a failed config doesn't touch the appenders in use
+func (lc *logConfig) linkFailovers() {
+	for _, app := range lc.appenders {
+		app.(*statAppender).setFailoverHandler(nil)
+	}
+	for _, app := range lc.appenders {
+		if fa, ok := app.(*statAppender).Appender.(*failoverAppender); ok {
+			fa.handlePrimaryErrors()
+		}
 	}
 }
 
+// getReusableAppender returns the appender of the replaced config, which was
+// created with the same attributes, or nil if there is no such one. Appenders
+// referring to other appenders are always re-created to refer to the new ones
+func (lc *logConfig) getReusableAppender(appName string, appAttributes map[string]string) Appender {
+	if lc.prev == nil || !equalParams(lc.prev.appenderParams[appName], appAttributes) {
+		return nil
+	}
+	app, ok := lc.prev.appenders[appName].(*statAppender)
+	if !ok {
+		return nil
+	}
+	if _, ok := app.Appender.(appenderLinker); ok {
+		return nil
+	}
+	return app
+}
+
 func (lc *logConfig) createContexts(params map[string]string) {
 	// collect settings for all contexts from config
 	ctxs := groupConfigParams(params, cfgContext, isCorrectLoggerName)
 
 	// create contexts
 	for logName, ctxAttributes := range ctxs {
+		lc.contextParams[logName] = ctxAttributes
 		appenders := lc.getAppendersFromList(ctxAttributes[cfgContextAppenders])
 		if len(appenders) == 0 {
 			panic("Context \"" + logName + "\" doesn't refer to at least one declared appender.")
@@ -194,7 +383,7 @@ func (lc *logConfig) createContexts(params map[string]string) {
 			}
 		}
 
-		bufSize, err := ParseInt64(ctxAttributes[cfgContextBufSize], 0, 100000, 100)
+		bufSize, err := ParseInt64(ctxAttributes[cfgContextBufSize], 1, maxContextBufSize, 100)
 		if err != nil {
 			panic("Incorrect buffer size=" + ctxAttributes[cfgContextBufSize] +
 				" value for context \"" + logName + "\" should be positive integer: " + err.Error())
@@ -210,10 +399,70 @@ func (lc *logConfig) createContexts(params map[string]string) {
 			panic("Incorrect context attibute " + cfgContextBlocking + " value, should be true or false")
 		}
 
+		caller, err := ParseBool(ctxAttributes[cfgContextCaller], false)
+		if err != nil {
+			panic("Incorrect context attibute " + cfgContextCaller + " value, should be true or false")
+		}
+
+		overflowStr, hasOverflow := ctxAttributes[cfgContextOverflow]
+		var overflow overflowPolicy
+		if hasOverflow {
+			overflow = lc.getOverflowPolicy(overflowStr, logName)
+		}
+
 		setLogLevel(level, logName, lc.logLevels)
+		lc.configLevels[logName] = true
 		context, _ := newLogContext(logName, appenders, inh, blocking, int(bufSize))
+		context.caller = caller
+		if hasOverflow {
+			context.overflow = overflow
+		}
 		lc.logContexts.Add(context)
 	}
+	lc.applyContextsInheritance()
+}
+
+// Makes every inherited context deliver its events to the appenders of the
+// nearest ancestor context. The contexts are sorted by names, so ancestors are
+// always processed before their descendants
+func (lc *logConfig) applyContextsInheritance() {
+	for _, c := range lc.logContexts.Copy() {
+		ctx := c.(*logContext)
+		parentName, ok := parentLoggerName(ctx.loggerName)
+		if !ctx.inherited || !ok {
+			continue
+		}
+		parent := getLogLevelContext(parentName, lc.logContexts)
+		if parent != nil {
+			ctx.setParent(parent)
+		}
+	}
+}
+
+// parses overflow policy value like dropOldest or dropBelowLevel=WARN
+func (lc *logConfig) getOverflowPolicy(value, logName string) overflowPolicy {
+	value = strings.Trim(value, " ")
+	policyName, levelName := value, ""
+	if idx := strings.Index(value, "="); idx >= 0 {
+		policyName, levelName = strings.Trim(value[:idx], " "), value[idx+1:]
+	}
+
+	policy, ok := overflowPolicies[policyName]
+	if !ok {
+		panic("Unknown overflow policy \"" + value + "\" for context \"" + logName +
+			"\", expected \"dropNewest\", \"dropOldest\", \"dropBelowLevel=<level>\" or \"block\"")
+	}
+
+	result := overflowPolicy{policy: policy}
+	if policy == ofDropBelowLevel {
+		result.level = lc.getLevelByName(levelName)
+		if result.level < 0 {
+			panic("Unknown log level \"" + levelName + "\" in overflow policy for context \"" + logName + "\"")
+		}
+	} else if levelName != "" {
+		panic("Unexpected level in overflow policy \"" + value + "\" for context \"" + logName + "\"")
+	}
+	return result
 }
 
 func (lc *logConfig) createLoggers(params map[string]string) {
@@ -222,27 +471,58 @@ func (lc *logConfig) createLoggers(params map[string]string) {
 
 	// apply logger settings
 	for loggerName, loggerAttributes := range loggers {
-		levelName := loggerAttributes[cfgLoggerLevel]
-		level := INFO
-		if len(levelName) > 0 {
-			level = lc.getLevelByName(levelName)
-			if level < 0 {
-				panic("Unknown log level \"" + levelName + "\" for logger \"" + loggerName + "\"")
+		if levelName, ok := loggerAttributes[cfgLoggerLevel]; ok {
+			level := INFO
+			if len(levelName) > 0 {
+				level = lc.getLevelByName(levelName)
+				if level < 0 {
+					panic("Unknown log level \"" + levelName + "\" for logger \"" + loggerName + "\"")
+				}
+			}
+			if durStr, ok := loggerAttributes[cfgLoggerLevelDuration]; ok {
+				lc.tempLevels = append(lc.tempLevels, lc.newLevelOverride(loggerName, level, durStr))
+				delete(lc.configLevels, loggerName)
+			} else {
+				lc.configLevels[loggerName] = true
+			}
+			setLogLevel(level, loggerName, lc.logLevels)
+		} else if _, ok := loggerAttributes[cfgLoggerLevelDuration]; ok {
+			panic("Logger attribute " + cfgLoggerLevelDuration + " for logger \"" + loggerName +
+				"\" should be specified with " + cfgLoggerLevel + " attribute")
+		}
+
+		if callerStr, ok := loggerAttributes[cfgLoggerCaller]; ok {
+			caller, err := ParseBool(callerStr, false)
+			if err != nil {
+				panic("Incorrect logger attibute " + cfgLoggerCaller + " value, should be true or false")
 			}
+			setCallerSetting(caller, loggerName, lc.callers)
 		}
-		setLogLevel(level, loggerName, lc.logLevels)
 		lc.getLogger(loggerName)
 	}
 }
 
 func (lc *logConfig) applyLevelsAndContexts() {
 	for _, l := range lc.loggers {
-		rootLLS := getLogLevelSetting(l.loggerName, lc.logLevels)
-		l.setLogLevelSetting(rootLLS)
+		l.setState(lc.newLoggerState(l.loggerName))
+	}
+}
+
+// builds the logger settings for the loggerName from the config
+func (lc *logConfig) newLoggerState(loggerName string) *loggerState {
+	rootLLS := getLogLevelSetting(loggerName, lc.logLevels)
+	rootCtx := getLogLevelContext(loggerName, lc.logContexts)
+	return &loggerState{rootLLS, rootCtx, rootLLS.level, lc.isCallerEnabled(loggerName, rootCtx)}
+}
 
-		lctx := getLogLevelContext(l.loggerName, lc.logContexts)
-		l.setLogContext(lctx)
+// caller location is captured if it is enabled for the logger context or
+// for the nearest ancestor of the logger in the logger settings
+func (lc *logConfig) isCallerEnabled(loggerName string, lctx *logContext) bool {
+	if lctx != nil && lctx.caller {
+		return true
 	}
+	cs := getCallerSetting(loggerName, lc.callers)
+	return cs != nil && cs.enabled
 }
 
 func (lc *logConfig) getLogger(loggerName string) Logger {
@@ -250,9 +530,7 @@ func (lc *logConfig) getLogger(loggerName string) Logger {
 	l, ok := lc.loggers[loggerName]
 	if !ok {
 		// Create new logger for the name
-		rootLLS := getLogLevelSetting(loggerName, lc.logLevels)
-		rootCtx := getLogLevelContext(loggerName, lc.logContexts)
-		l = &logger{loggerName, rootLLS, rootCtx, rootLLS.level}
+		l = newLogger(loggerName, lc.newLoggerState(loggerName))
 		lc.loggers[loggerName] = l
 	}
 	return l
@@ -263,6 +541,27 @@ func (lc *logConfig) setLogLevel(level Level, loggerName string) {
 	applyNewLevelToLoggers(lls, lc.loggers)
 }
 
+// resetLogLevel removes the level setting of the loggerName, so the loggers
+// which used it inherit the level of the nearest ancestor setting. The root
+// logger setting is never removed. Returns false if there is no setting to remove
+func (lc *logConfig) resetLogLevel(loggerName string) bool {
+	if loggerName == rootLoggerName || resetLogLevel(loggerName, lc.logLevels) == nil {
+		return false
+	}
+	applyLevelToSubtree(getLogLevelSetting(loggerName, lc.logLevels), loggerName, lc.loggers)
+	return true
+}
+
+// getLogLevel returns the effective level of the loggerName and whether it
+// is set for the name explicitly
+func (lc *logConfig) getLogLevel(loggerName string) (Level, bool) {
+	lls := getLogLevelSetting(loggerName, lc.logLevels)
+	if lls == nil {
+		return INFO, false
+	}
+	return lls.level, lls.loggerName == loggerName
+}
+
 func (lc *logConfig) registerAppender(appenderFactory AppenderFactory) error {
 	appenderName := appenderFactory.Name()
 	_, ok := lc.appenderFactorys[appenderName]
@@ -294,6 +593,16 @@ func (lc *logConfig) getAppendersFromList(appNames string) []Appender {
 	return result
 }
 
+// setLevelName associates the level with the name, so the level can be
+// referred by the name in the config
+func (lc *logConfig) setLevelName(level Level, name string) {
+	delete(lc.levelMap, strings.Trim(strings.ToLower(lc.levelNames[level]), " "))
+	lc.levelNames[level] = name
+	if levelName := strings.Trim(strings.ToLower(name), " "); len(levelName) > 0 {
+		lc.levelMap[levelName] = level
+	}
+}
+
 // gets level index, or -1 if not found
 func (lc *logConfig) getLevelByName(levelName string) (idx Level) {
 	levelName = strings.Trim(strings.ToLower(levelName), " ")
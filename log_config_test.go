@@ -19,6 +19,8 @@ func (s *logConfigSuite) TestGetLevelByName(c *C) {
 	c.Assert(lc.getLevelByName(lc.levelNames[INFO]), Equals, INFO)
 	c.Assert(lc.getLevelByName(lc.levelNames[DEBUG]), Equals, DEBUG)
 	c.Assert(lc.getLevelByName(lc.levelNames[TRACE]), Equals, TRACE)
+	c.Assert(lc.levelNames[WARN], Equals, "WARN")
+	c.Assert(lc.getLevelByName("all"), Equals, ALL)
 }
 
 func (s *logConfigSuite) TestMergedParamsWithDefault(c *C) {
@@ -86,12 +88,50 @@ func (s *logConfigSuite) TestSetLogLevel(c *C) {
 	l := lc.getLogger("a")
 
 	lc.setLogLevel(DEBUG, "a.b")
-	c.Assert(lc.getLogger("a.b.c").(*logger).logLevel, Equals, DEBUG)
-	c.Assert(l.(*logger).logLevel, Equals, INFO)
+	c.Assert(lc.getLogger("a.b.c").(*logger).getState().logLevel, Equals, DEBUG)
+	c.Assert(l.(*logger).getState().logLevel, Equals, INFO)
 
 	lc.setLogLevel(WARN, "a")
-	c.Assert(l.(*logger).logLevel, Equals, WARN)
-	c.Assert(lc.getLogger("a.b.c").(*logger).logLevel, Equals, DEBUG)
+	c.Assert(l.(*logger).getState().logLevel, Equals, WARN)
+	c.Assert(lc.getLogger("a.b.c").(*logger).getState().logLevel, Equals, DEBUG)
+}
+
+func (s *logConfigSuite) TestResetLogLevel(c *C) {
+	lc := newLogConfig()
+	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	lc.initIfNeeded()
+
+	abc := lc.getLogger("a.b.c").(*logger)
+	abcd := lc.getLogger("a.b.c.d").(*logger)
+	lc.setLogLevel(WARN, "a")
+	lc.setLogLevel(DEBUG, "a.b")
+	lc.setLogLevel(TRACE, "a.b.c.d")
+
+	level, explicit := lc.getLogLevel("a.b.c")
+	c.Assert(level, Equals, DEBUG)
+	c.Assert(explicit, Equals, false)
+	level, explicit = lc.getLogLevel("a.b")
+	c.Assert(level, Equals, DEBUG)
+	c.Assert(explicit, Equals, true)
+
+	c.Assert(lc.resetLogLevel("a.b"), Equals, true)
+	c.Assert(lc.resetLogLevel("a.b"), Equals, false)
+	c.Assert(abc.getState().logLevel, Equals, WARN)
+	c.Assert(abc.getState().lls.loggerName, Equals, "a")
+	c.Assert(abcd.getState().logLevel, Equals, TRACE)
+	level, explicit = lc.getLogLevel("a.b")
+	c.Assert(level, Equals, WARN)
+	c.Assert(explicit, Equals, false)
+
+	c.Assert(lc.resetLogLevel("a"), Equals, true)
+	c.Assert(abc.getState().logLevel, Equals, INFO)
+	c.Assert(abc.getState().lls.loggerName, Equals, rootLoggerName)
+
+	// the root level is kept
+	c.Assert(lc.resetLogLevel(rootLoggerName), Equals, false)
+	level, explicit = lc.getLogLevel(rootLoggerName)
+	c.Assert(level, Equals, INFO)
+	c.Assert(explicit, Equals, true)
 }
 
 func (s *logConfigSuite) TestGetLogger(c *C) {
@@ -116,8 +156,8 @@ func (s *logConfigSuite) TestCreateLoggers(c *C) {
 		"logger.b.c.d.level": "DEBUG",
 	}
 	lc.createLoggers(params)
-	c.Assert(lc.getLogger("a.b.c").(*logger).logLevel, Equals, TRACE)
-	c.Assert(lc.getLogger("b.c.d").(*logger).logLevel, Equals, DEBUG)
+	c.Assert(lc.getLogger("a.b.c").(*logger).getState().logLevel, Equals, TRACE)
+	c.Assert(lc.getLogger("b.c.d").(*logger).getState().logLevel, Equals, DEBUG)
 
 	pnc := checkPanic(
 		func() {
@@ -145,9 +185,74 @@ func (s *logConfigSuite) TestCreateContexts(c *C) {
 		"context.a.b.c.inherited": "true3"})
 	panicWhenCreateContext(c, lc, map[string]string{"context.a.b.c.appenders": "ROOT",
 		"context.a.b.c.blocking": "true3"})
+	panicWhenCreateContext(c, lc, map[string]string{"context.a.b.c.appenders": "ROOT",
+		"context.a.b.c.overflow": "dropAll"})
+	panicWhenCreateContext(c, lc, map[string]string{"context.a.b.c.appenders": "ROOT",
+		"context.a.b.c.overflow": "dropBelowLevel=ABC"})
+	panicWhenCreateContext(c, lc, map[string]string{"context.a.b.c.appenders": "ROOT",
+		"context.a.b.c.overflow": "dropOldest=WARN"})
 	lc.createContexts(nil)
 }
 
+func (s *logConfigSuite) TestOverflowPolicy(c *C) {
+	lc := newLogConfig()
+	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	lc.initIfNeeded()
+
+	lc.createContexts(map[string]string{
+		"context.a.appenders": "ROOT",
+		"context.b.appenders": "ROOT", "context.b.blocking": "false",
+		"context.c.appenders": "ROOT", "context.c.overflow": "dropOldest",
+		"context.d.appenders": "ROOT", "context.d.overflow": " dropBelowLevel = warn", "context.d.blocking": "false",
+		"context.e.appenders": "ROOT", "context.e.overflow": "block", "context.e.blocking": "false"})
+	c.Assert(getLogLevelContext("a", lc.logContexts).overflow, Equals, overflowPolicy{ofBlock, 0})
+	c.Assert(getLogLevelContext("b", lc.logContexts).overflow, Equals, overflowPolicy{ofDropNewest, 0})
+	c.Assert(getLogLevelContext("c", lc.logContexts).overflow, Equals, overflowPolicy{ofDropOldest, 0})
+	c.Assert(getLogLevelContext("d", lc.logContexts).overflow, Equals, overflowPolicy{ofDropBelowLevel, WARN})
+	c.Assert(getLogLevelContext("e", lc.logContexts).overflow, Equals, overflowPolicy{ofBlock, 0})
+	lc.cleanUp()
+}
+
+func (s *logConfigSuite) TestContextsInheritance(c *C) {
+	lc := newLogConfig()
+	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	lc.setConfigParams(map[string]string{
+		"appender.ROOT.type":        consoleAppenderName,
+		"appender.AA.type":          consoleAppenderName,
+		"appender.BB.type":          consoleAppenderName,
+		"context.appenders":         "ROOT",
+		"context.a.appenders":       "AA",
+		"context.a.b.appenders":     "BB,ROOT",
+		"context.a.b.c.appenders":   "BB",
+		"context.a.b.c.inherited":   "false",
+		"context.a.b.c.d.appenders": "ROOT"})
+
+	root := getLogLevelContext("", lc.logContexts)
+	a := getLogLevelContext("a", lc.logContexts)
+	ab := getLogLevelContext("a.b", lc.logContexts)
+	abc := getLogLevelContext("a.b.c", lc.logContexts)
+	abcd := getLogLevelContext("a.b.c.d", lc.logContexts)
+
+	c.Assert(root.parent, IsNil)
+	checkAppenders(c, root.targets, lc.appenders["ROOT"])
+	c.Assert(a.parent, Equals, root)
+	checkAppenders(c, a.targets, lc.appenders["AA"], lc.appenders["ROOT"])
+	c.Assert(ab.parent, Equals, a)
+	checkAppenders(c, ab.targets, lc.appenders["BB"], lc.appenders["ROOT"], lc.appenders["AA"])
+	c.Assert(abc.parent, IsNil)
+	checkAppenders(c, abc.targets, lc.appenders["BB"])
+	c.Assert(abcd.parent, Equals, abc)
+	checkAppenders(c, abcd.targets, lc.appenders["ROOT"], lc.appenders["BB"])
+	lc.cleanUp()
+}
+
+func checkAppenders(c *C, appenders []Appender, expected ...Appender) {
+	c.Assert(len(appenders), Equals, len(expected))
+	for i, a := range expected {
+		c.Assert(appenders[i], Equals, a)
+	}
+}
+
 func panicWhenCreateContext(c *C, lc *logConfig, params map[string]string) {
 	pnc := checkPanic(
 		func() {
@@ -177,6 +282,44 @@ func (s *logConfigSuite) TestCreateAppenders(c *C) {
 	c.Assert(pnc, Equals, true)
 }
 
+func (s *logConfigSuite) TestReuseAppenders(c *C) {
+	oldConfig := newLogConfig()
+	c.Assert(oldConfig.registerAppender(&shutdownAppenderFactory{}), IsNil)
+	c.Assert(oldConfig.registerAppender(&failoverAppenderFactory{}), IsNil)
+	oldConfig.setConfigParams(map[string]string{
+		"appender.AA.type": shutdownAppenderName, "appender.AA.p": "1",
+		"appender.BB.type": shutdownAppenderName,
+		"appender.CC.type": shutdownAppenderName,
+		"appender.FF.type": failoverAppenderName, "appender.FF.primary": "AA", "appender.FF.secondaries": "BB",
+		"context.appenders": "AA"})
+
+	lc := newLogConfig()
+	lc.initWithParams(oldConfig, map[string]string{
+		"appender.AA.type": shutdownAppenderName, "appender.AA.p": "1",
+		"appender.BB.type": shutdownAppenderName, "appender.BB.p": "2",
+		"appender.FF.type": failoverAppenderName, "appender.FF.primary": "AA", "appender.FF.secondaries": "BB",
+		"context.appenders": "AA"})
+	c.Assert(lc.prev, IsNil)
+	c.Assert(lc.appenders["AA"], Equals, oldConfig.appenders["AA"])
+	c.Assert(lc.appenders["BB"], Not(Equals), oldConfig.appenders["BB"])
+	// failover appender is re-created to refer the new BB
+	c.Assert(lc.appenders["FF"], Not(Equals), oldConfig.appenders["FF"])
+	c.Assert(lc.appenders["FF"].(*statAppender).Appender.(*failoverAppender).secondaries[0], Equals, lc.appenders["BB"])
+
+	oldConfig.replaceBy(lc)
+	shutDown := func(appenders map[string]Appender, name string) bool {
+		return appenders[name].(*statAppender).Appender.(*shutdownAppender).shutdown
+	}
+	c.Assert(shutDown(oldConfig.appenders, "AA"), Equals, false)
+	c.Assert(shutDown(oldConfig.appenders, "BB"), Equals, true)
+	c.Assert(shutDown(oldConfig.appenders, "CC"), Equals, true)
+	c.Assert(shutDown(lc.appenders, "BB"), Equals, false)
+	c.Assert(getLogLevelContext("", oldConfig.logContexts).successors.Load(), Equals, lc.logContexts)
+
+	lc.cleanUp()
+	c.Assert(shutDown(lc.appenders, "AA"), Equals, true)
+}
+
 func (s *logConfigSuite) TestApplyLevelParams(c *C) {
 	lc := newLogConfig()
 	c.Assert(lc.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
@@ -216,6 +359,36 @@ type testAppenderFactory struct {
 	name string
 }
 
+const shutdownAppenderName = "test/shutdownAppender"
+
+// shutdownAppender remembers whether it is shut down
+type shutdownAppender struct {
+	testAppender
+	shutdown bool
+}
+
+type shutdownAppenderFactory struct {
+	// the appenders created by the factory
+	created []*shutdownAppender
+}
+
+func (sa *shutdownAppender) Shutdown() {
+	sa.shutdown = true
+}
+
+func (saf *shutdownAppenderFactory) Name() string {
+	return shutdownAppenderName
+}
+
+func (saf *shutdownAppenderFactory) NewAppender(params map[string]string) (Appender, error) {
+	sa := &shutdownAppender{}
+	saf.created = append(saf.created, sa)
+	return sa, nil
+}
+
+func (saf *shutdownAppenderFactory) Shutdown() {
+}
+
 func (taf *testAppenderFactory) Name() string {
 	return taf.name
 }
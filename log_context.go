@@ -1,18 +1,74 @@
 package log4g
 
 import (
+	"context"
 	"errors"
 	"github.com/dspasibenko/log4g/collections"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// overflow policies define what the context does with a new log event when
+// its events channel is full
+const (
+	// the new event is dropped
+	ofDropNewest = iota
+	// the oldest event in the channel is dropped to free space for the new one
+	ofDropOldest
+	// the new event is dropped if its level is lower than the policy level,
+	// otherwise the logger waits until the event can be put into the channel
+	ofDropBelowLevel
+	// the logger waits until the event can be put into the channel
+	ofBlock
+)
+
+// the overflow policies names, dropBelowLevel is followed by the level name
+// like dropBelowLevel=WARN
+var overflowPolicies = map[string]int{"dropNewest": ofDropNewest, "dropOldest": ofDropOldest,
+	"dropBelowLevel": ofDropBelowLevel, "block": ofBlock}
+
+type overflowPolicy struct {
+	policy int
+	// events with this level or higher priority are not dropped by ofDropBelowLevel policy
+	level Level
+}
+
 type logContext struct {
 	loggerName string
 	appenders  []Appender
-	inherited  bool
-	blocking   bool
-	eventsCh   chan *LogEvent
-	controlCh  chan bool
+	// the nearest ancestor context the events are propagated to if inherited is true
+	parent *logContext
+	// appenders of the context and its ancestors the events are delivered to,
+	// every appender is listed once
+	targets   []Appender
+	inherited bool
+	blocking  bool
+	eventsCh  chan *LogEvent
+	// closed when the context is shut down. eventsCh is never closed by the
+	// context, so senders don't race with the shutdown
+	doneCh    chan bool
+	stopOnce  sync.Once
+	flushCh   chan chan bool
+	controlCh chan bool
+	// whether the caller location is captured for all loggers of the context
+	caller   bool
+	overflow overflowPolicy
+	// total number of dropped events
+	dropped atomic.Uint64
+	// number of dropped events which were not reported by the synthetic event yet
+	unreported atomic.Uint64
+	// number of events put into the channel and delivered to the appenders
+	enqueued  atomic.Uint64
+	processed atomic.Uint64
+	// total time of the events delivery in nanoseconds
+	processingTime atomic.Int64
+	// number of go routines which are sending events into the channel
+	senders atomic.Int32
+	// contexts of the configuration which replaced the context. The events
+	// logged after the context is shut down are handed over to them
+	successors atomic.Pointer[collections.SortedSlice]
 }
 
 func newLogContext(loggerName string, appenders []Appender, inherited, blocking bool, bufSize int) (*logContext, error) {
@@ -25,22 +81,64 @@ func newLogContext(loggerName string, appenders []Appender, inherited, blocking
 	}
 
 	eventsCh := make(chan *LogEvent, bufSize)
+	doneCh := make(chan bool)
+	flushCh := make(chan chan bool)
 	controlCh := make(chan bool, 1)
-	lc := &logContext{loggerName, appenders, inherited, blocking, eventsCh, controlCh}
+	lc := &logContext{loggerName: loggerName, appenders: appenders, targets: appenders, inherited: inherited,
+		blocking: blocking, eventsCh: eventsCh, doneCh: doneCh, flushCh: flushCh, controlCh: controlCh}
+	if blocking {
+		lc.overflow.policy = ofBlock
+	}
 
 	go func() {
 		defer onStop(controlCh)
 		for {
-			le, ok := <-eventsCh
-			if !ok {
-				break
+			select {
+			case le, ok := <-eventsCh:
+				if !ok {
+					return
+				}
+				lc.onEvent(le)
+				lc.reportDrops()
+			case req := <-flushCh:
+				lc.drain()
+				close(req)
+			case <-doneCh:
+				lc.waitSenders()
+				lc.drain()
+				return
 			}
-			lc.onEvent(le)
 		}
 	}()
 	return lc, nil
 }
 
+// delivers events which were put into the channel before the shutdown or flush
+func (lc *logContext) drain() {
+	for {
+		select {
+		case le := <-lc.eventsCh:
+			lc.onEvent(le)
+		default:
+			lc.reportDrops()
+			return
+		}
+	}
+}
+
+// reportDrops delivers a synthetic event about the dropped events when the
+// overflow is over, what means the events channel is less than half full.
+// Called from processing go routine
+func (lc *logContext) reportDrops() {
+	if lc.unreported.Load() == 0 || len(lc.eventsCh) > cap(lc.eventsCh)/2 {
+		return
+	}
+	dropped := lc.unreported.Swap(0)
+	msg := strconv.FormatUint(dropped, 10) + " events dropped"
+	lc.onEvent(&LogEvent{Level: WARN, Timestamp: time.Now(), LoggerName: lc.loggerName, Payload: msg,
+		Fields: []Field{{"dropped", dropped}}})
+}
+
 // Processing go routine finalizer
 func onStop(controlCh chan bool) {
 	controlCh <- true
@@ -55,42 +153,169 @@ func getLogLevelContext(loggerName string, logContexts *collections.SortedSlice)
 	return lProvider.(*logContext)
 }
 
+// setParent makes the events of the context be delivered to the parent
+// context appenders as well. The parent targets should be set before the call.
+func (lc *logContext) setParent(parent *logContext) {
+	lc.parent = parent
+	targets := make([]Appender, len(lc.appenders), len(lc.appenders)+len(parent.targets))
+	copy(targets, lc.appenders)
+	for _, a := range parent.targets {
+		if !containsAppender(targets, a) {
+			targets = append(targets, a)
+		}
+	}
+	lc.targets = targets
+}
+
+func containsAppender(appenders []Appender, appender Appender) bool {
+	for _, a := range appenders {
+		if a == appender {
+			return true
+		}
+	}
+	return false
+}
+
 // log() function sends the logEvent to all the logContext appenders.
-// It returns true if the logEvent was sent and false if the context is shut down or
-// the context is non-blocking (allows to lost log messages in case of overflow)
-func (lc *logContext) log(le *LogEvent) (result bool) {
-	// Channel can be already closed, so end quietly
-	result = false
-	defer EndQuietly()
-
-	if lc.blocking {
-		lc.eventsCh <- le
-		return true
+// It returns true if the logEvent was sent and false if the event is dropped
+// because of the context overflow policy. If the context is shut down, the
+// event is handed over to the context which replaced it
+func (lc *logContext) log(le *LogEvent) bool {
+	sent, done := lc.send(le)
+	if done {
+		return lc.handOver(le)
+	}
+	return sent
+}
+
+// send puts the event into the channel according to the overflow policy.
+// done is true if the event is not sent because the context is shut down
+func (lc *logContext) send(le *LogEvent) (sent, done bool) {
+	lc.senders.Add(1)
+	defer lc.senders.Add(-1)
+
+	select {
+	case <-lc.doneCh:
+		return false, true
+	default:
 	}
 
 	select {
 	case lc.eventsCh <- le:
-		return true
+		lc.enqueued.Add(1)
+		return true, false
 	default:
 	}
-	return false
+
+	// the channel is full
+	switch lc.overflow.policy {
+	case ofBlock:
+		return lc.put(le)
+	case ofDropBelowLevel:
+		if le.Level <= lc.overflow.level {
+			return lc.put(le)
+		}
+	case ofDropOldest:
+		for {
+			select {
+			case lc.eventsCh <- le:
+				lc.enqueued.Add(1)
+				return true, false
+			case <-lc.doneCh:
+				return false, true
+			default:
+			}
+			select {
+			case <-lc.eventsCh:
+				lc.onDrop()
+			default:
+			}
+		}
+	}
+	lc.onDrop()
+	return false, false
+}
+
+// waits until the event is put into the channel or the context is shut down
+func (lc *logContext) put(le *LogEvent) (sent, done bool) {
+	select {
+	case lc.eventsCh <- le:
+		lc.enqueued.Add(1)
+		return true, false
+	case <-lc.doneCh:
+		return false, true
+	}
+}
+
+// handOver sends the event to the successor context for the event logger
+// name. It returns false if there is no successor
+func (lc *logContext) handOver(le *LogEvent) bool {
+	successors := lc.successors.Load()
+	if successors == nil {
+		return false
+	}
+	next := getLogLevelContext(le.LoggerName, successors)
+	if next == nil {
+		return false
+	}
+	return next.log(le)
+}
+
+// waits until all go routines which could see the context alive finish
+// sending their events. Called from processing go routine after doneCh is closed
+func (lc *logContext) waitSenders() {
+	for lc.senders.Load() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (lc *logContext) onDrop() {
+	lc.dropped.Add(1)
+	lc.unreported.Add(1)
 }
 
 // Called from processing go routine
 func (lc *logContext) onEvent(le *LogEvent) {
-	appenders := lc.appenders
+	start := time.Now()
+	appenders := lc.targets
 	if len(appenders) == 1 {
 		appenders[0].Append(le)
-		return
-	}
-	for _, a := range appenders {
-		a.Append(le)
+	} else {
+		for _, a := range appenders {
+			a.Append(le)
+		}
 	}
+	lc.processingTime.Add(int64(time.Since(start)))
+	lc.processed.Add(1)
+}
+
+// flush waits until all events put into the context before the call are
+// passed to its appenders
+func (lc *logContext) flush(ctx context.Context) error {
+	return requestFlush(ctx, lc.flushCh, lc.doneCh)
 }
 
 func (lc *logContext) shutdown() {
-	close(lc.eventsCh)
-	<-lc.controlCh
+	lc.shutdownContext(context.Background())
+}
+
+// shutdownContext stops the context and waits until all events put into the
+// context before the call are passed to its appenders. It stops waiting and
+// returns false if ctx is done earlier.
+func (lc *logContext) shutdownContext(ctx context.Context) bool {
+	lc.stopOnce.Do(func() { close(lc.doneCh) })
+	select {
+	case <-lc.controlCh:
+		return true
+	default:
+	}
+
+	select {
+	case <-lc.controlCh:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // logNameProvider implementation
@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	"github.com/dspasibenko/log4g/collections"
 	. "gopkg.in/check.v1"
 	"time"
@@ -94,6 +95,132 @@ func (s *logContextSuite) TestGetLogLevelContext(c *C) {
 	c.Assert(getLogLevelContext("b", ss).loggerName, Equals, "b")
 }
 
+func (s *logContextSuite) TestSetParent(c *C) {
+	a1, a2, a3 := &testAppender{"a1"}, &testAppender{"a2"}, &testAppender{"a3"}
+	root := &logContext{loggerName: "", appenders: []Appender{a1}, targets: []Appender{a1}}
+	a := &logContext{loggerName: "a", appenders: []Appender{a2, a1}, targets: []Appender{a2, a1}}
+	ab := &logContext{loggerName: "a.b", appenders: []Appender{a3}, targets: []Appender{a3}}
+
+	a.setParent(root)
+	c.Assert(a.parent, Equals, root)
+	c.Assert(a.targets, DeepEquals, []Appender{a2, a1})
+
+	ab.setParent(a)
+	c.Assert(ab.targets, DeepEquals, []Appender{a3, a2, a1})
+	c.Assert(ab.appenders, DeepEquals, []Appender{a3})
+}
+
+func (s *logContextSuite) TestInheritedOnEvent(c *C) {
+	s.logEvents = make([]*LogEvent, 0, 10)
+	root, _ := newLogContext("", []Appender{s}, true, true, 1)
+	lc, _ := newLogContext("abc", []Appender{s}, true, true, 1)
+	lc.setParent(root)
+
+	le := new(LogEvent)
+	lc.onEvent(le)
+	c.Assert(len(s.logEvents), Equals, 1)
+	root.shutdown()
+	lc.shutdown()
+}
+
+func (s *logContextSuite) TestFlush(c *C) {
+	s.logEvents = make([]*LogEvent, 0, 100)
+	s.hasSleep = true
+	defer func() { s.hasSleep = false }()
+	lc, _ := newLogContext("abc", []Appender{s}, true, true, 100)
+
+	for i := 0; i < 50; i++ {
+		lc.log(new(LogEvent))
+	}
+	c.Assert(lc.flush(context.Background()), IsNil)
+	c.Assert(len(s.logEvents), Equals, 50)
+
+	lc.shutdown()
+	c.Assert(lc.flush(context.Background()), IsNil)
+	c.Assert(lc.log(new(LogEvent)), Equals, false)
+}
+
+func (s *logContextSuite) TestOverflowDropNewest(c *C) {
+	lc := &logContext{eventsCh: make(chan *LogEvent, 2)}
+	les := []*LogEvent{{Level: INFO}, {Level: INFO}, {Level: FATAL}}
+	c.Assert(lc.log(les[0]), Equals, true)
+	c.Assert(lc.log(les[1]), Equals, true)
+	c.Assert(lc.log(les[2]), Equals, false)
+	c.Assert(lc.dropped.Load(), Equals, uint64(1))
+	c.Assert(<-lc.eventsCh, Equals, les[0])
+	c.Assert(<-lc.eventsCh, Equals, les[1])
+}
+
+func (s *logContextSuite) TestOverflowDropOldest(c *C) {
+	lc := &logContext{eventsCh: make(chan *LogEvent, 2), overflow: overflowPolicy{policy: ofDropOldest}}
+	les := []*LogEvent{{Level: INFO}, {Level: INFO}, {Level: INFO}, {Level: INFO}}
+	for _, le := range les {
+		c.Assert(lc.log(le), Equals, true)
+	}
+	c.Assert(lc.dropped.Load(), Equals, uint64(2))
+	c.Assert(<-lc.eventsCh, Equals, les[2])
+	c.Assert(<-lc.eventsCh, Equals, les[3])
+}
+
+func (s *logContextSuite) TestOverflowDropBelowLevel(c *C) {
+	lc := &logContext{eventsCh: make(chan *LogEvent, 1), overflow: overflowPolicy{ofDropBelowLevel, WARN}}
+	c.Assert(lc.log(&LogEvent{Level: INFO}), Equals, true)
+	c.Assert(lc.log(&LogEvent{Level: INFO}), Equals, false)
+	c.Assert(lc.log(&LogEvent{Level: DEBUG}), Equals, false)
+	c.Assert(lc.dropped.Load(), Equals, uint64(2))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-lc.eventsCh
+	}()
+	le := &LogEvent{Level: WARN}
+	c.Assert(lc.log(le), Equals, true)
+	c.Assert(<-lc.eventsCh, Equals, le)
+}
+
+func (s *logContextSuite) TestReportDrops(c *C) {
+	s.logEvents = make([]*LogEvent, 0, 10)
+	lc := &logContext{loggerName: "a", eventsCh: make(chan *LogEvent, 4), targets: []Appender{s}}
+	lc.reportDrops()
+	c.Assert(len(s.logEvents), Equals, 0)
+
+	for i := 0; i < 7; i++ {
+		lc.log(&LogEvent{Level: INFO})
+	}
+	c.Assert(lc.unreported.Load(), Equals, uint64(3))
+	// the overflow is not over yet
+	lc.reportDrops()
+	c.Assert(len(s.logEvents), Equals, 0)
+
+	<-lc.eventsCh
+	<-lc.eventsCh
+	lc.reportDrops()
+	c.Assert(len(s.logEvents), Equals, 1)
+	c.Assert(s.logEvents[0].Level, Equals, WARN)
+	c.Assert(s.logEvents[0].LoggerName, Equals, "a")
+	c.Assert(s.logEvents[0].Payload, Equals, "3 events dropped")
+	c.Assert(s.logEvents[0].Fields, DeepEquals, []Field{{"dropped", uint64(3)}})
+	c.Assert(lc.unreported.Load(), Equals, uint64(0))
+	c.Assert(lc.dropped.Load(), Equals, uint64(3))
+}
+
+func (s *logContextSuite) TestHandOver(c *C) {
+	s.logEvents = make([]*LogEvent, 0, 10)
+	lc, _ := newLogContext("a", []Appender{s}, true, true, 10)
+	next := &logContext{loggerName: "a", eventsCh: make(chan *LogEvent, 1)}
+	successors, _ := collections.NewSortedSliceByParams(next)
+	lc.shutdown()
+
+	le := &LogEvent{LoggerName: "a.b"}
+	c.Assert(lc.log(le), Equals, false)
+	lc.successors.Store(successors)
+	c.Assert(lc.log(le), Equals, true)
+	c.Assert(<-next.eventsCh, Equals, le)
+	// there is no successor context for the logger name
+	c.Assert(lc.log(&LogEvent{LoggerName: "b"}), Equals, false)
+	c.Assert(len(s.logEvents), Equals, 0)
+}
+
 func (lcs *logContextSuite) Append(logEvent *LogEvent) bool {
 	lcs.logEvents = append(lcs.logEvents, logEvent)
 	if lcs.hasSleep {
@@ -27,6 +27,16 @@ func setLogLevel(level Level, loggerName string, logLevels *collections.SortedSl
 	return lls
 }
 
+// Removes level setting for the provided loggerName, returns the removed
+// setting or nil if there is no setting for the name
+func resetLogLevel(loggerName string, logLevels *collections.SortedSlice) *logLevelSetting {
+	idx, found := logLevels.Find(&logLevelSetting{loggerName: loggerName})
+	if !found {
+		return nil
+	}
+	return logLevels.DeleteAt(idx).(*logLevelSetting)
+}
+
 func getLogLevelSetting(loggerName string, logLevels *collections.SortedSlice) *logLevelSetting {
 	lProvider := getNearestAncestor(&logLevelSetting{loggerName: loggerName}, logLevels)
 	if lProvider == nil {
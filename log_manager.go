@@ -1,27 +1,42 @@
 package log4g
 
 import (
-	"bufio"
+	"context"
 	"errors"
-	"os"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
 type logManager struct {
 	config *logConfig
 	rwLock sync.RWMutex
+	// loggers created so far by their normalized names. Loggers are kept
+	// between configurations, so the map allows to get an existing logger
+	// without locking
+	loggers sync.Map
+	// watches the config file if it is set by watchConfigF()
+	watcher *configWatcher
+	// the temporary levels by the logger names
+	overrides map[string]*levelOverride
+	// handles signals if it is set by handleSignals()
+	signals *signalHandler
 }
 
 var lm *logManager = &logManager{config: newLogConfig()}
 
 func (lm *logManager) getLogger(loggerName string) Logger {
+	loggerName = normalizeLogName(loggerName)
+	if l, ok := lm.loggers.Load(loggerName); ok {
+		return l.(Logger)
+	}
+
 	lm.rwLock.Lock()
 	defer lm.rwLock.Unlock()
 
 	lm.config.initIfNeeded()
-	return lm.config.getLogger(loggerName)
+	l := lm.config.getLogger(loggerName)
+	lm.loggers.Store(loggerName, l)
+	return l
 }
 
 func (lm *logManager) setLogLevel(loggerName string, level Level) {
@@ -29,9 +44,28 @@ func (lm *logManager) setLogLevel(loggerName string, level Level) {
 	defer lm.rwLock.Unlock()
 
 	lm.config.initIfNeeded()
+	lm.cancelOverride(normalizeLogName(loggerName))
 	lm.config.setLogLevel(level, loggerName)
 }
 
+func (lm *logManager) getLogLevel(loggerName string) (Level, bool) {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	lm.config.initIfNeeded()
+	return lm.config.getLogLevel(normalizeLogName(loggerName))
+}
+
+func (lm *logManager) resetLogLevel(loggerName string) bool {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	lm.config.initIfNeeded()
+	loggerName = normalizeLogName(loggerName)
+	lm.cancelOverride(loggerName)
+	return lm.config.resetLogLevel(loggerName)
+}
+
 func (lm *logManager) registerAppender(appenderFactory AppenderFactory) error {
 	lm.rwLock.Lock()
 	defer lm.rwLock.Unlock()
@@ -39,69 +73,152 @@ func (lm *logManager) registerAppender(appenderFactory AppenderFactory) error {
 	return lm.config.registerAppender(appenderFactory)
 }
 
-func (lm *logManager) shutdown() {
+func (lm *logManager) shutdown(ctx context.Context) error {
+	lm.rwLock.Lock()
+	cw := lm.watcher
+	lm.watcher = nil
+	lm.rwLock.Unlock()
+	// the watcher could be applying the config, so it is stopped without the
+	// lock, and cannot apply the config after the shutdown
+	if cw != nil {
+		cw.stop()
+	}
+
 	lm.rwLock.Lock()
 	defer lm.rwLock.Unlock()
 
-	lm.config.cleanUp()
-	for _, af := range lm.config.appenderFactorys {
-		af.Shutdown()
+	lm.cancelOverrides()
+	if lm.signals != nil {
+		lm.signals.stop()
+		lm.signals = nil
 	}
+	return lm.config.shutdown(ctx)
+}
+
+func (lm *logManager) flush(ctx context.Context) error {
+	lm.rwLock.RLock()
+	defer lm.rwLock.RUnlock()
+
+	return lm.config.flush(ctx)
+}
+
+func (lm *logManager) stats() *Statistics {
+	lm.rwLock.RLock()
+	defer lm.rwLock.RUnlock()
+
+	return lm.config.stats()
+}
+
+func (lm *logManager) effectiveConfig() map[string]string {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	lm.config.initIfNeeded()
+	return lm.config.effectiveConfig(lm.overrides)
+}
+
+func (lm *logManager) loggersInfo() []LoggerInfo {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	lm.config.initIfNeeded()
+	return lm.config.loggersInfo()
 }
 
 func (lm *logManager) setPropsFromFile(configFileName string) error {
-	f, err := os.Open(configFileName)
+	props, err := loadPropsFile(configFileName, nil)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	props := map[string]string{}
-	scanner := bufio.NewScanner(f)
-	lineNum := 1
-	for ; scanner.Scan(); lineNum++ {
-		line := strings.TrimLeft(scanner.Text(), " ")
-		if len(line) == 0 || strings.Index(line, "#") == 0 {
-			continue
-		}
-
-		idx := strings.Index(line, "=")
-		if idx < 0 {
-			return errors.New("Unexpected value in line " + strconv.Itoa(lineNum) + ": \"" + line +
-				"\" in the config file " + configFileName +
-				". key-value pair should be specified in the form <key>=<value>")
-		}
+	return lm.setNewProperties(props)
+}
 
-		props[strings.TrimRight(line[:idx], " ")] = line[idx+1:]
+// watchConfigF applies the config file and starts checking it for changes
+// every interval. The previous watcher is stopped.
+func (lm *logManager) watchConfigF(configFileName string, interval time.Duration, onError func(err error)) error {
+	if interval <= 0 {
+		return errors.New("Cannot watch config file " + configFileName + ": interval should be positive")
+	}
+	if err := lm.setConfigFile(configFileName); err != nil {
+		return err
 	}
 
-	return lm.setNewProperties(props)
+	cw := newConfigWatcher(lm, configFileName, onError)
+	lm.rwLock.Lock()
+	prev := lm.watcher
+	lm.watcher = cw
+	go cw.run(interval)
+	lm.rwLock.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+	return nil
 }
 
 func (lm *logManager) setNewProperties(props map[string]string) (err error) {
+	props, err = substituteProps(props)
+	if err != nil {
+		return err
+	}
+
+	// the events about the temporary levels are sent when the lock is released
+	var senders []func()
+	defer func() {
+		for _, send := range senders {
+			send()
+		}
+	}()
+
 	lm.rwLock.Lock()
 	defer lm.rwLock.Unlock()
 
+	config := newLogConfig()
+	oldConfig := lm.config
 	defer func() {
 		p := recover()
 		if p == nil {
 			return
 		}
+		// the contexts and appenders created for the failed config are not
+		// used by anybody, the reused appenders are kept
+		config.shutdownExcept(context.Background(), oldConfig.appenders, nil)
 		err = errors.New(p.(string))
 	}()
-	config := newLogConfig()
-	oldConfig := lm.config
 	config.initWithParams(oldConfig, props)
 
 	lm.config = config
-	oldConfig.cleanUp()
+	setLogLevelNames(config.levelNames)
+	config.linkFailovers()
+	oldConfig.replaceBy(config)
+	// the overrides must not revert the levels set by the new config
+	for name := range config.configLevels {
+		lm.cancelOverride(name)
+	}
+	for _, lo := range config.tempLevels {
+		senders = append(senders, lm.startOverride(lo))
+	}
+	config.tempLevels = nil
 	return
 }
 
 func (lm *logManager) setLogLevelName(level int, name string) bool {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
 	if level < 0 || level >= len(lm.config.levelNames) {
 		return false
 	}
-	lm.config.levelNames[level] = name
+	lm.config.setLevelName(Level(level), name)
+	setLogLevelNames(lm.config.levelNames)
 	return true
 }
+
+func (lm *logManager) getLevelByName(levelName string) (Level, bool) {
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	lm.config.initIfNeeded()
+	level := lm.config.getLevelByName(levelName)
+	return level, level >= 0
+}
@@ -0,0 +1,178 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type logManagerSuite struct {
+}
+
+var _ = Suite(&logManagerSuite{})
+
+func newTestLogManager(c *C) *logManager {
+	m := &logManager{config: newLogConfig()}
+	c.Assert(m.registerAppender(&testAppenderFactory{consoleAppenderName}), IsNil)
+	return m
+}
+
+func (s *logManagerSuite) TestGetLogger(c *C) {
+	m := newTestLogManager(c)
+	l := m.getLogger("a.b")
+	c.Assert(m.getLogger(" a.b."), Equals, l)
+	c.Assert(m.getLogger("a"), Not(Equals), l)
+
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": consoleAppenderName,
+		"context.appenders": "ROOT", "context.level": "DEBUG"}), IsNil)
+	c.Assert(m.getLogger("a.b"), Equals, l)
+	c.Assert(l.(*logger).getState().logLevel, Equals, DEBUG)
+	m.shutdown(context.Background())
+}
+
+// The test makes sense with -race flag mostly
+func (s *logManagerSuite) TestConcurrentReconfiguration(c *C) {
+	m := newTestLogManager(c)
+	props := map[string]string{"appender.ROOT.type": consoleAppenderName, "context.appenders": "ROOT"}
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.getLogger("a.b." + strconv.Itoa(i%10)).Info("Hello")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.getLogger("a.b").Debugw("Hello", "i", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.setLogLevel("a", Level(INFO+Level(i%2)*levelStep))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			c.Check(m.setNewProperties(props), IsNil)
+			m.setLogLevelName(int(INFO), "INFO")
+		}
+	}()
+	wg.Wait()
+	m.shutdown(context.Background())
+}
+
+func (s *logManagerSuite) TestFlush(c *C) {
+	m := newTestLogManager(c)
+	fa := &flushAppender{}
+	m.config.appenders["flush"] = fa
+	c.Assert(m.flush(context.Background()), IsNil)
+	c.Assert(fa.flushes, Equals, 1)
+
+	fa.err = context.Canceled
+	c.Assert(m.flush(context.Background()), Equals, context.Canceled)
+	m.shutdown(context.Background())
+}
+
+type flushAppender struct {
+	testAppender
+	flushes int
+	err     error
+}
+
+func (fa *flushAppender) Flush(ctx context.Context) error {
+	fa.flushes++
+	return fa.err
+}
+
+func (s *logManagerSuite) TestShutdownContext(c *C) {
+	m := newTestLogManager(c)
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": consoleAppenderName,
+		"context.appenders": "ROOT", "context.buffer": "10", "context.a.appenders": "ROOT"}), IsNil)
+	ba := &blockingAppender{make(chan bool)}
+	m.config.appenders["blocking"] = ba
+	lctx := getLogLevelContext("a", m.config.logContexts)
+	lctx.targets = []Appender{ba}
+	for i := 0; i < 5; i++ {
+		m.getLogger("a").Info("Hello")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.shutdown(ctx)
+	c.Assert(err, NotNil)
+	// the root context and its appender are not blocked by "a"
+	se := err.(*ShutdownError)
+	c.Assert(se.Contexts, DeepEquals, []string{"a"})
+	c.Assert(se.Appenders, DeepEquals, []string{"blocking"})
+	c.Assert(se.Queued, Equals, 4)
+	close(ba.unblock)
+
+	m = newTestLogManager(c)
+	m.getLogger("a").Info("Hello")
+	c.Assert(m.shutdown(context.Background()), IsNil)
+}
+
+func (s *logManagerSuite) TestShutdownError(c *C) {
+	se := &ShutdownError{Contexts: []string{"", "a"}, Appenders: []string{"file"}, Queued: 4}
+	c.Assert(se.Error(), Equals, "log4g shutdown is not completed: contexts [\"\", \"a\"] and appenders [\"file\"] "+
+		"are not finished, 4 events are still queued")
+	se.AppenderFactories = []string{"test/blocking"}
+	c.Assert(se.Error(), Equals, "log4g shutdown is not completed: contexts [\"\", \"a\"], appenders [\"file\"] "+
+		"and appender factories [\"test/blocking\"] are not finished, 4 events are still queued")
+}
+
+// blockingFactory blocks its Shutdown() until unblock is closed
+type blockingFactory struct {
+	testAppenderFactory
+	unblock chan bool
+}
+
+func (bf *blockingFactory) Shutdown() {
+	<-bf.unblock
+}
+
+func (s *logManagerSuite) TestShutdownFactoryTimeout(c *C) {
+	m := newTestLogManager(c)
+	bf := &blockingFactory{testAppenderFactory{"test/blocking"}, make(chan bool)}
+	defer close(bf.unblock)
+	c.Assert(m.registerAppender(bf), IsNil)
+	c.Assert(m.setNewProperties(map[string]string{"appender.ROOT.type": consoleAppenderName,
+		"context.appenders": "ROOT"}), IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.shutdown(ctx)
+	c.Assert(err, NotNil)
+	se := err.(*ShutdownError)
+	c.Assert(se.AppenderFactories, DeepEquals, []string{"test/blocking"})
+	c.Assert(len(se.Contexts), Equals, 0)
+	c.Assert(len(se.Appenders), Equals, 0)
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+type blockingAppender struct {
+	unblock chan bool
+}
+
+func (ba *blockingAppender) Append(event *LogEvent) bool {
+	<-ba.unblock
+	return true
+}
+
+func (ba *blockingAppender) Shutdown() {
+	<-ba.unblock
+}
@@ -1,9 +1,11 @@
 package log4g
 
 import (
+	"context"
 	"errors"
 	"github.com/dspasibenko/log4g/collections"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -49,6 +51,19 @@ func ancestor(checkedName, loggerName string) bool {
 	return false
 }
 
+// Gets the parent name for the loggerName, returns false if the loggerName is
+// the root logger name which has no parent
+func parentLoggerName(loggerName string) (string, bool) {
+	if loggerName == rootLoggerName {
+		return "", false
+	}
+	idx := strings.LastIndex(loggerName, ".")
+	if idx < 0 {
+		return rootLoggerName, true
+	}
+	return loggerName[:idx], true
+}
+
 func getNearestAncestor(comparator collections.Comparator, names *collections.SortedSlice) logNameProvider {
 	if names.Len() == 0 {
 		return nil
@@ -200,6 +215,93 @@ func getSuffix(value string, suffixes []string) (int, string) {
 	return -1, ""
 }
 
+// requestFlush sends flush request to a processing go routine via flushCh and
+// waits until the go routine closes the request channel. The go routine is
+// considered stopped (so nothing to flush) when doneCh is closed
+func requestFlush(ctx context.Context, flushCh chan chan bool, doneCh <-chan bool) error {
+	req := make(chan bool)
+	select {
+	case flushCh <- req:
+	case <-doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// queuedCounter can be implemented by appenders which queue messages, to
+// report the number of messages not written yet
+type queuedCounter interface {
+	queued() int
+}
+
+// waitFor runs f in a separate go routine and waits until it is over. It
+// returns false if ctx is done before that. Panics in f are ignored.
+func waitFor(ctx context.Context, f func()) bool {
+	doneCh := make(chan bool)
+	go func() {
+		defer close(doneCh)
+		defer EndQuietly()
+		f()
+	}()
+
+	select {
+	case <-doneCh:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// waitForAll runs the functions concurrently and waits until they are over.
+// It returns the sorted names of the functions which were not over when ctx
+// is done. Panics in the functions are ignored.
+func waitForAll(ctx context.Context, fs map[string]func()) []string {
+	doneChs := make(map[string]chan bool, len(fs))
+	for name, f := range fs {
+		doneCh := make(chan bool)
+		go func(f func()) {
+			defer close(doneCh)
+			defer EndQuietly()
+			f()
+		}(f)
+		doneChs[name] = doneCh
+	}
+
+	var result []string
+	for name, doneCh := range doneChs {
+		select {
+		case <-doneCh:
+		case <-ctx.Done():
+			// the function could be over as well
+			select {
+			case <-doneCh:
+			default:
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// quotes every name and joins them by comma. Used to make the root logger
+// name visible in messages
+func quoteAndJoin(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = strconv.Quote(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
 // Utility methods
 func Min(a, b int) int {
 	if a < b {
@@ -210,6 +312,30 @@ func Min(a, b int) int {
 	return a
 }
 
+// equalParams returns whether both maps contain the same keys and values.
+// nil map is not equal to any map
+func equalParams(p1, p2 map[string]string) bool {
+	if p1 == nil || p2 == nil || len(p1) != len(p2) {
+		return false
+	}
+	for k, v := range p1 {
+		if v2, ok := p2[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the keys of the map in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func EndQuietly() {
 	recover()
 }
@@ -1,6 +1,7 @@
 package log4g
 
 import (
+	"context"
 	"github.com/dspasibenko/log4g/collections"
 	. "gopkg.in/check.v1"
 )
@@ -19,6 +20,28 @@ func (s *nameUtilsSuite) TestAncestor(c *C) {
 	c.Assert(ancestor("a.b", "a.c.c"), Equals, false)
 }
 
+func (s *nameUtilsSuite) TestParentLoggerName(c *C) {
+	_, ok := parentLoggerName("")
+	c.Assert(ok, Equals, false)
+
+	name, ok := parentLoggerName("a")
+	c.Assert(ok, Equals, true)
+	c.Assert(name, Equals, "")
+
+	name, _ = parentLoggerName("a.b.c")
+	c.Assert(name, Equals, "a.b")
+}
+
+func (s *nameUtilsSuite) TestEqualParams(c *C) {
+	c.Assert(equalParams(nil, nil), Equals, false)
+	c.Assert(equalParams(nil, map[string]string{}), Equals, false)
+	c.Assert(equalParams(map[string]string{}, map[string]string{}), Equals, true)
+	c.Assert(equalParams(map[string]string{"a": "1"}, map[string]string{"a": "1"}), Equals, true)
+	c.Assert(equalParams(map[string]string{"a": "1"}, map[string]string{"a": "2"}), Equals, false)
+	c.Assert(equalParams(map[string]string{"a": "1"}, map[string]string{"b": "1"}), Equals, false)
+	c.Assert(equalParams(map[string]string{"a": "1"}, map[string]string{"a": "1", "b": "1"}), Equals, false)
+}
+
 func (s *nameUtilsSuite) TestGetSetLogLevel(c *C) {
 	ss, _ := collections.NewSortedSlice(2)
 	c.Assert(getNearestAncestor(&nameUtilsSuite{"a"}, ss), IsNil)
@@ -185,6 +208,23 @@ func (nus *nameUtilsSuite) Compare(other collections.Comparator) int {
 	return compare(nus, other.(*nameUtilsSuite))
 }
 
+func (s *nameUtilsSuite) TestRequestFlush(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(requestFlush(ctx, make(chan chan bool), nil), Equals, context.Canceled)
+
+	doneCh := make(chan bool)
+	close(doneCh)
+	c.Assert(requestFlush(context.Background(), make(chan chan bool), doneCh), IsNil)
+
+	flushCh := make(chan chan bool)
+	go func() {
+		req := <-flushCh
+		close(req)
+	}()
+	c.Assert(requestFlush(context.Background(), flushCh, nil), IsNil)
+}
+
 func checkPanic(f func()) (result bool) {
 	defer func() {
 		result = recover() != nil
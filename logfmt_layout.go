@@ -0,0 +1,234 @@
+package log4g
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// writes the event in logfmt form like:
+//
+//	ts=2024-03-01T10:20:30.123Z level=info logger=a.b msg="Hello world" key=value
+//
+// A payload which is a map with string keys or a struct is flattened into
+// key=value pairs instead of msg, the nested maps and structs keys are joined
+// by dots. Errors, fmt.Stringer values and structs without exported fields
+// are written as text. The event fields follow the payload, if there are several fields
+// with the same key, the last one is written
+func writeLogfmt(buf *bytes.Buffer, logEvent *LogEvent) {
+	buf.WriteString("ts=")
+	writeLogfmtValue(buf, logEvent.Timestamp.Format(defaultJSONTimeFormat))
+	buf.WriteString(" level=")
+	writeLogfmtValue(buf, strings.ToLower(eventLevelName(logEvent.Level)))
+	buf.WriteString(" logger=")
+	writeLogfmtValue(buf, logEvent.LoggerName)
+	if logEvent.Caller != nil {
+		buf.WriteString(" caller=")
+		writeLogfmtValue(buf, filepath.Base(logEvent.Caller.File)+":"+strconv.Itoa(logEvent.Caller.Line))
+	}
+
+	if !writeLogfmtStruct(buf, "", reflect.ValueOf(logEvent.Payload), map[uintptr]bool{}) {
+		buf.WriteString(" msg=")
+		writeLogfmtValue(buf, logEvent.Payload)
+	}
+
+	for i, f := range logEvent.Fields {
+		if !isOverridden(logEvent.Fields, i) {
+			writeLogfmtPair(buf, f.Key, f.Value)
+		}
+	}
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteByte(' ')
+	writeLogfmtKey(buf, key)
+	buf.WriteByte('=')
+	writeLogfmtValue(buf, value)
+}
+
+// writes the fields of the struct or the entries of the map with string keys
+// as key=value pairs. Returns false if v is not flattened, see logfmtFlattened.
+// visited contains the pointers and the maps of the current path to detect
+// the cycles
+func writeLogfmtStruct(buf *bytes.Buffer, prefix string, v reflect.Value, visited map[uintptr]bool) bool {
+	v, path, ok := logfmtFlattened(v, visited)
+	if !ok {
+		return false
+	}
+	defer func() {
+		for _, p := range path {
+			delete(visited, p)
+		}
+	}()
+
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			writeLogfmtMember(buf, prefix+k.String(), v.MapIndex(k), visited)
+		}
+		return true
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); isLogfmtField(f) {
+			writeLogfmtMember(buf, prefix+fieldName(f), v.Field(i), visited)
+		}
+	}
+	return true
+}
+
+// returns the struct or the map with string keys which v refers to and the
+// pointers added to visited on the way. The value is not flattened if it is
+// nil, implements error or fmt.Stringer, is an empty map, a struct without
+// exported fields, or refers to a visited pointer
+func logfmtFlattened(v reflect.Value, visited map[uintptr]bool) (reflect.Value, []uintptr, bool) {
+	var path []uintptr
+	for {
+		if _, ok := logfmtText(v); ok || !v.IsValid() {
+			break
+		}
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+			continue
+		case reflect.Pointer:
+			if v.IsNil() || visited[v.Pointer()] {
+				break
+			}
+			visited[v.Pointer()] = true
+			path = append(path, v.Pointer())
+			v = v.Elem()
+			continue
+		case reflect.Map:
+			if v.Type().Key().Kind() == reflect.String && v.Len() > 0 && !visited[v.Pointer()] {
+				visited[v.Pointer()] = true
+				return v, append(path, v.Pointer()), true
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if isLogfmtField(v.Type().Field(i)) {
+					return v, path, true
+				}
+			}
+		}
+		break
+	}
+
+	for _, p := range path {
+		delete(visited, p)
+	}
+	return v, nil, false
+}
+
+// returns the value which is written as text by its Error() or String()
+// method, the methods may have pointer receiver
+func logfmtText(v reflect.Value) (interface{}, bool) {
+	if v.IsValid() && v.CanInterface() {
+		switch val := v.Interface().(type) {
+		case error, fmt.Stringer:
+			return val, true
+		}
+	}
+	if v.CanAddr() && v.Addr().CanInterface() {
+		switch val := v.Addr().Interface().(type) {
+		case error, fmt.Stringer:
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+func isLogfmtField(f reflect.StructField) bool {
+	return f.IsExported() && fieldName(f) != "-"
+}
+
+// writes the nested structs and maps with the key prefix, and other values as key=value
+func writeLogfmtMember(buf *bytes.Buffer, key string, v reflect.Value, visited map[uintptr]bool) {
+	if writeLogfmtStruct(buf, key+".", v, visited) {
+		return
+	}
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	value, ok := logfmtText(v)
+	switch {
+	case ok:
+	case (v.Kind() == reflect.Pointer || v.Kind() == reflect.Map) && visited[v.Pointer()]:
+		// the cycle, fmt.Sprint() could not stop on it
+		value = fmt.Sprintf("%p", v.Interface())
+	case v.IsValid() && v.CanInterface():
+		value = v.Interface()
+	}
+	writeLogfmtPair(buf, key, value)
+}
+
+// the name of the struct field is taken from its json tag if it is specified
+func fieldName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// logfmt keys cannot contain spaces, '=', '"' and control characters, they
+// are replaced by '_'
+func writeLogfmtKey(buf *bytes.Buffer, key string) {
+	if key == "" {
+		buf.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// writes the value as is if it doesn't need quoting, otherwise writes it as
+// quoted string with escaped quotes, backslashes and control characters
+func writeLogfmtValue(buf *bytes.Buffer, v interface{}) {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+		return
+	case string:
+		s = val
+	case error:
+		s = val.Error()
+	default:
+		s = fmt.Sprint(val)
+	}
+
+	if needsLogfmtQuoting(s) {
+		writeJSONString(buf, s)
+		return
+	}
+	buf.WriteString(s)
+}
+
+func needsLogfmtQuoting(s string) bool {
+	if s == "" || !utf8.ValidString(s) {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == '\u2028' || r == '\u2029' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package log4g
+
+import (
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"strconv"
+	"time"
+)
+
+type logfmtLayoutSuite struct {
+}
+
+var _ = Suite(&logfmtLayoutSuite{})
+
+type logfmtPayload struct {
+	User    string `json:"user"`
+	Age     int
+	Skipped string `json:"-"`
+	secret  string
+	Address struct {
+		City string `json:"city,omitempty"`
+	}
+}
+
+func (s *logfmtLayoutSuite) TestLogfmtLayout(c *C) {
+	t, err := parseLayoutParams("", map[string]string{"layoutType": "logfmt"})
+	c.Assert(err, IsNil)
+
+	le := &LogEvent{Level: INFO, Timestamp: jsonTestTime, LoggerName: "a.b", Payload: "Hello world",
+		Fields: []Field{{"id", 12}, {"err", errors.New("x=1")}, {"id", 13}, {"bad key", ""}, {"n", nil}}}
+	c.Assert(ToLogMessage(le, t), Equals, `ts=2024-03-01T10:20:30.123Z level=info logger=a.b msg="Hello world" `+
+		`err="x=1" id=13 bad_key="" n=null`)
+
+	le = &LogEvent{Level: WARN, Timestamp: jsonTestTime, Payload: "ok",
+		Caller: &CallerInfo{File: "/src/a/b.go", Line: 12}}
+	c.Assert(ToLogMessage(le, t), Equals, `ts=2024-03-01T10:20:30.123Z level=warn logger="" caller=b.go:12 msg=ok`)
+}
+
+func (s *logfmtLayoutSuite) TestQuoting(c *C) {
+	values := map[string]string{
+		"plain":       `plain`,
+		"":            `""`,
+		"two words":   `"two words"`,
+		"a=b":         `"a=b"`,
+		`say "hi"`:    `"say \"hi\""`,
+		"line\nbreak": `"line\nbreak"`,
+		`back\slash`:  `"back\\slash"`,
+		"tab\there":   `"tab\there"`,
+		"bad\xff":     `"bad\ufffd"`,
+	}
+	for v, exp := range values {
+		le := &LogEvent{Level: INFO, Timestamp: jsonTestTime, LoggerName: "a", Payload: v}
+		c.Assert(ToLogMessage(le, LayoutTemplate{{pieceType: lpLogfmt}}), Equals,
+			"ts=2024-03-01T10:20:30.123Z level=info logger=a msg="+exp)
+	}
+}
+
+func (s *logfmtLayoutSuite) TestFlattening(c *C) {
+	t, _ := parseLayoutParams("", map[string]string{"layoutType": "logfmt"})
+
+	le := &LogEvent{Level: DEBUG, Timestamp: jsonTestTime, LoggerName: "a",
+		Payload: map[string]interface{}{"b": "x y", "a": 1, "m": map[string]interface{}{"k": true}},
+		Fields:  []Field{{"f", 1.5}}}
+	c.Assert(ToLogMessage(le, t), Equals,
+		`ts=2024-03-01T10:20:30.123Z level=debug logger=a a=1 b="x y" m.k=true f=1.5`)
+
+	p := logfmtPayload{User: "john", Age: 33, Skipped: "s", secret: "s"}
+	p.Address.City = "New York"
+	le.Payload, le.Fields = &p, nil
+	c.Assert(ToLogMessage(le, t), Equals,
+		`ts=2024-03-01T10:20:30.123Z level=debug logger=a user=john Age=33 Address.city="New York"`)
+
+	le.Payload = []int{1, 2}
+	c.Assert(ToLogMessage(le, t), Equals, `ts=2024-03-01T10:20:30.123Z level=debug logger=a msg="[1 2]"`)
+	le.Payload = (*logfmtPayload)(nil)
+	c.Assert(ToLogMessage(le, t), Equals, `ts=2024-03-01T10:20:30.123Z level=debug logger=a msg=<nil>`)
+}
+
+type logfmtNode struct {
+	Name string
+	Next *logfmtNode
+}
+
+type logfmtPtrStringer struct {
+	ID int
+}
+
+func (ps *logfmtPtrStringer) String() string {
+	return "id-" + strconv.Itoa(ps.ID)
+}
+
+type logfmtHidden struct {
+	id int
+}
+
+func (s *logfmtLayoutSuite) TestLogfmtPayloads(c *C) {
+	t, _ := parseLayoutParams("", map[string]string{"layoutType": "logfmt"})
+	le := &LogEvent{Level: ERROR, Timestamp: jsonTestTime, LoggerName: "a"}
+	prefix := "ts=2024-03-01T10:20:30.123Z level=error logger=a "
+
+	// errors and stringers are written as msg
+	le.Payload = errors.New("disk is full")
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`msg="disk is full"`)
+	le.Payload = &logfmtPtrStringer{ID: 7}
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`msg=id-7`)
+	le.Payload = &struct {
+		Err error
+		PS  logfmtPtrStringer
+		T   time.Duration
+	}{errors.New("x y"), logfmtPtrStringer{ID: 3}, time.Second}
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`Err="x y" PS=id-3 T=1s`)
+
+	// no exported fields
+	le.Payload = logfmtHidden{id: 5}
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`msg={5}`)
+	le.Payload = map[string]int{}
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`msg=map[]`)
+
+	// cycles
+	n := &logfmtNode{Name: "a"}
+	n.Next = &logfmtNode{Name: "b", Next: n}
+	le.Payload = n
+	c.Assert(ToLogMessage(le, t), Equals, prefix+fmt.Sprintf("Name=a Next.Name=b Next.Next=%p", n))
+	m := map[string]interface{}{"k": 1}
+	m["self"] = m
+	le.Payload = m
+	c.Assert(ToLogMessage(le, t), Equals, prefix+fmt.Sprintf("k=1 self=%p", m))
+
+	// the same pointer can be met twice outside of a cycle
+	shared := &logfmtNode{Name: "s"}
+	le.Payload = map[string]*logfmtNode{"x": shared, "y": shared}
+	c.Assert(ToLogMessage(le, t), Equals, prefix+`x.Name=s x.Next=<nil> y.Name=s y.Next=<nil>`)
+}
@@ -1,87 +1,257 @@
 package log4g
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+// loggerState is an immutable snapshot of the logger settings. It is replaced
+// as a whole when the settings are changed, so the logging methods can read
+// it concurrently with reconfiguration without any locks
+type loggerState struct {
+	lls      *logLevelSetting
+	lctx     *logContext
+	logLevel Level
+	// whether the caller location should be captured for the logger events
+	caller bool
+}
+
 type logger struct {
 	loggerName string
-	lls        *logLevelSetting
-	lctx       *logContext
-	logLevel   Level
+	state      atomic.Pointer[loggerState]
+	// fields bound to the logger by With()
+	fields []Field
+	// additional frames to skip when the caller location is captured
+	skip int
+	// the logger which holds level and context settings. It is nil for loggers
+	// created by GetLogger() and refers to the original logger for ones created by With()
+	base *logger
+}
+
+func newLogger(loggerName string, state *loggerState) *logger {
+	l := &logger{loggerName: loggerName}
+	l.state.Store(state)
+	return l
 }
 
 func (l *logger) Fatal(args ...interface{}) {
-	l.Log(FATAL, args...)
+	l.log(nil, FATAL, args)
 }
 
 func (l *logger) Error(args ...interface{}) {
-	l.Log(ERROR, args...)
+	l.log(nil, ERROR, args)
 }
 
 func (l *logger) Warn(args ...interface{}) {
-	l.Log(WARN, args...)
+	l.log(nil, WARN, args)
 }
 
 func (l *logger) Info(args ...interface{}) {
-	l.Log(INFO, args...)
+	l.log(nil, INFO, args)
 }
 
 func (l *logger) Debug(args ...interface{}) {
-	l.Log(DEBUG, args...)
+	l.log(nil, DEBUG, args)
 }
 
 func (l *logger) Trace(args ...interface{}) {
-	l.Log(TRACE, args...)
+	l.log(nil, TRACE, args)
 }
 
 func (l *logger) Log(level Level, args ...interface{}) {
-	if l.logLevel < level {
+	l.log(nil, level, args)
+}
+
+func (l *logger) Logf(level Level, fstr string, args ...interface{}) {
+	l.logf(nil, level, fstr, args)
+}
+
+func (l *logger) Logp(level Level, payload interface{}) {
+	l.logp(nil, level, payload)
+}
+
+func (l *logger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, FATAL, msg, keysAndValues)
+}
+
+func (l *logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, ERROR, msg, keysAndValues)
+}
+
+func (l *logger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, WARN, msg, keysAndValues)
+}
+
+func (l *logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, INFO, msg, keysAndValues)
+}
+
+func (l *logger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, DEBUG, msg, keysAndValues)
+}
+
+func (l *logger) Tracew(msg string, keysAndValues ...interface{}) {
+	l.logw(nil, TRACE, msg, keysAndValues)
+}
+
+func (l *logger) Logw(level Level, msg string, keysAndValues ...interface{}) {
+	l.logw(nil, level, msg, keysAndValues)
+}
+
+func (l *logger) FatalCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, FATAL, args)
+}
+
+func (l *logger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, ERROR, args)
+}
+
+func (l *logger) WarnCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, WARN, args)
+}
+
+func (l *logger) InfoCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, INFO, args)
+}
+
+func (l *logger) DebugCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, DEBUG, args)
+}
+
+func (l *logger) TraceCtx(ctx context.Context, args ...interface{}) {
+	l.log(ctx, TRACE, args)
+}
+
+func (l *logger) LogCtx(ctx context.Context, level Level, args ...interface{}) {
+	l.log(ctx, level, args)
+}
+
+func (l *logger) LogfCtx(ctx context.Context, level Level, fstr string, args ...interface{}) {
+	l.logf(ctx, level, fstr, args)
+}
+
+func (l *logger) LogwCtx(ctx context.Context, level Level, msg string, keysAndValues ...interface{}) {
+	l.logw(ctx, level, msg, keysAndValues)
+}
+
+func (l *logger) With(keysAndValues ...interface{}) Logger {
+	return &logger{loggerName: l.loggerName, fields: appendFields(l.fields, keysAndValues),
+		skip: l.skip, base: l.settings()}
+}
+
+func (l *logger) WithCallerSkip(skip int) Logger {
+	return &logger{loggerName: l.loggerName, fields: l.fields, skip: l.skip + skip, base: l.settings()}
+}
+
+// The internal methods below must be called directly from the Logger
+// methods, so the caller location is always callerSkip frames above
+// logInternal
+func (l *logger) log(ctx context.Context, level Level, args []interface{}) {
+	if l.getState().logLevel < level {
 		return
 	}
-	l.logInternal(level, fmt.Sprint(args...))
+	l.logInternal(level, fmt.Sprint(args...), withContextFields(ctx, l.fields))
 }
 
-func (l *logger) Logf(level Level, fstr string, args ...interface{}) {
-	if l.logLevel < level {
+func (l *logger) logf(ctx context.Context, level Level, fstr string, args []interface{}) {
+	if l.getState().logLevel < level {
 		return
 	}
 	msg := fstr
 	if len(args) > 0 {
 		msg = fmt.Sprintf(fstr, args...)
 	}
-	l.logInternal(level, msg)
+	l.logInternal(level, msg, withContextFields(ctx, l.fields))
 }
 
-func (l *logger) Logp(level Level, payload interface{}) {
-	if l.logLevel < level {
+func (l *logger) logp(ctx context.Context, level Level, payload interface{}) {
+	if l.getState().logLevel < level {
 		return
 	}
-	l.logInternal(level, payload)
+	l.logInternal(level, payload, withContextFields(ctx, l.fields))
 }
 
-func (l *logger) logInternal(level Level, payload interface{}) {
-	l.lctx.log(&LogEvent{level, time.Now(), l.loggerName, payload})
+func (l *logger) logw(ctx context.Context, level Level, msg string, keysAndValues []interface{}) {
+	if l.getState().logLevel < level {
+		return
+	}
+	l.logInternal(level, msg, appendFields(withContextFields(ctx, l.fields), keysAndValues))
 }
 
-func (l *logger) setLogLevelSetting(lls *logLevelSetting) {
-	l.lls = lls
-	l.logLevel = lls.level
+func (l *logger) logInternal(level Level, payload interface{}, fields []Field) {
+	s := l.getState()
+	le := &LogEvent{Level: level, Timestamp: time.Now(), LoggerName: l.loggerName, Payload: payload, Fields: fields}
+	if s.caller {
+		le.Caller = captureCaller(callerSkip + l.skip)
+	}
+	s.lctx.log(le)
 }
 
-func (l *logger) setLogContext(lctx *logContext) {
-	l.lctx = lctx
+// returns the logger which keeps the level and context settings
+func (l *logger) settings() *logger {
+	if l.base != nil {
+		return l.base
+	}
+	return l
+}
+
+// returns current settings of the logger
+func (l *logger) getState() *loggerState {
+	return l.settings().state.Load()
+}
+
+// The setters below must be called under the logManager lock, which
+// guarantees no updates are lost between loading and storing the state
+func (l *logger) setState(state *loggerState) {
+	l.state.Store(state)
+}
+
+func (l *logger) setLogLevelSetting(lls *logLevelSetting) {
+	state := *l.state.Load()
+	state.lls = lls
+	state.logLevel = lls.level
+	l.state.Store(&state)
 }
 
 // Apply new LogLevelSetting to all appropriate loggers
 func applyNewLevelToLoggers(lls *logLevelSetting, loggers map[string]*logger) {
+	applyLevelToSubtree(lls, lls.loggerName, loggers)
+}
+
+// Apply the LogLevelSetting to the loggers of the subtree with the rootName,
+// which don't use more specific settings of the subtree
+func applyLevelToSubtree(lls *logLevelSetting, rootName string, loggers map[string]*logger) {
 	for _, l := range loggers {
-		if !ancestor(lls.loggerName, l.loggerName) {
+		if !ancestor(rootName, l.loggerName) {
 			continue
 		}
-		if ancestor(l.lls.loggerName, lls.loggerName) {
+		if ancestor(l.getState().lls.loggerName, rootName) {
 			l.setLogLevelSetting(lls)
 		}
 	}
 }
+
+// appendFields returns new slice which contains the fields followed by the
+// fields built from keysAndValues pairs. The fields slice is never modified,
+// so it can be safely shared between loggers and log events
+func appendFields(fields []Field, keysAndValues []interface{}) []Field {
+	if len(keysAndValues) == 0 {
+		return fields
+	}
+	result := make([]Field, len(fields), len(fields)+(len(keysAndValues)+1)/2)
+	copy(result, fields)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		result = append(result, Field{key, value})
+	}
+	return result
+}
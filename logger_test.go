@@ -15,28 +15,28 @@ func (s *loggerSuite) TestApplyNewLevelToLoggers(c *C) {
 	rootLLS := &logLevelSetting{rootLoggerName, INFO}
 
 	loggers := make(map[string]*logger)
-	loggers["a"] = &logger{"a", rootLLS, nil, INFO}
-	loggers["a.b"] = &logger{"a.b", rootLLS, nil, INFO}
-	loggers["a.b.c"] = &logger{"a.b.c", rootLLS, nil, INFO}
-	loggers["a.b.c.d"] = &logger{"a.b.c.d", rootLLS, nil, INFO}
+	loggers["a"] = newLogger("a", &loggerState{lls: rootLLS, logLevel: INFO})
+	loggers["a.b"] = newLogger("a.b", &loggerState{lls: rootLLS, logLevel: INFO})
+	loggers["a.b.c"] = newLogger("a.b.c", &loggerState{lls: rootLLS, logLevel: INFO})
+	loggers["a.b.c.d"] = newLogger("a.b.c.d", &loggerState{lls: rootLLS, logLevel: INFO})
 
 	applyNewLevelToLoggers(&logLevelSetting{"a.b", DEBUG}, loggers)
-	c.Assert(loggers["a"].logLevel, Equals, INFO)
-	c.Assert(loggers["a.b"].logLevel, Equals, DEBUG)
-	c.Assert(loggers["a.b.c"].logLevel, Equals, DEBUG)
-	c.Assert(loggers["a.b.c.d"].logLevel, Equals, DEBUG)
+	c.Assert(loggers["a"].getState().logLevel, Equals, INFO)
+	c.Assert(loggers["a.b"].getState().logLevel, Equals, DEBUG)
+	c.Assert(loggers["a.b.c"].getState().logLevel, Equals, DEBUG)
+	c.Assert(loggers["a.b.c.d"].getState().logLevel, Equals, DEBUG)
 
 	applyNewLevelToLoggers(&logLevelSetting{"a.b.c", TRACE}, loggers)
 	applyNewLevelToLoggers(&logLevelSetting{"a.b", ERROR}, loggers)
-	c.Assert(loggers["a"].logLevel, Equals, INFO)
-	c.Assert(loggers["a.b"].logLevel, Equals, ERROR)
-	c.Assert(loggers["a.b.c"].logLevel, Equals, TRACE)
-	c.Assert(loggers["a.b.c.d"].logLevel, Equals, TRACE)
+	c.Assert(loggers["a"].getState().logLevel, Equals, INFO)
+	c.Assert(loggers["a.b"].getState().logLevel, Equals, ERROR)
+	c.Assert(loggers["a.b.c"].getState().logLevel, Equals, TRACE)
+	c.Assert(loggers["a.b.c.d"].getState().logLevel, Equals, TRACE)
 }
 
 func (s *loggerSuite) TestLog(c *C) {
 	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
-	l := &logger{"a", nil, lctx, INFO}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
 	l.Log(INFO, "Hello")
 	go waitThenClose(500, lctx)
 	le, ok := <-lctx.eventsCh
@@ -49,7 +49,7 @@ func (s *loggerSuite) TestLog(c *C) {
 
 func (s *loggerSuite) TestLogDisabled(c *C) {
 	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
-	l := &logger{"a", nil, lctx, INFO}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
 	l.Log(DEBUG, "Hello")
 	go waitThenClose(50, lctx)
 	_, ok := <-lctx.eventsCh
@@ -58,7 +58,7 @@ func (s *loggerSuite) TestLogDisabled(c *C) {
 
 func (s *loggerSuite) TestLogf(c *C) {
 	lctx := &logContext{eventsCh: make(chan *LogEvent, 2)}
-	l := &logger{"a", nil, lctx, INFO}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
 	l.Logf(INFO, "Hello %s")
 	l.Logf(INFO, "Hello %s", "World!")
 	go waitThenClose(500, lctx)
@@ -73,7 +73,7 @@ func (s *loggerSuite) TestLogf(c *C) {
 
 func (s *loggerSuite) TestLogp(c *C) {
 	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
-	l := &logger{"a", nil, lctx, INFO}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
 	l.Logp(INFO, lctx)
 	go waitThenClose(500, lctx)
 	le, ok := <-lctx.eventsCh
@@ -83,7 +83,7 @@ func (s *loggerSuite) TestLogp(c *C) {
 
 func (s *loggerSuite) TestMessages(c *C) {
 	lctx := &logContext{eventsCh: make(chan *LogEvent, 10)}
-	l := &logger{"a", nil, lctx, TRACE}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: TRACE})
 	l.Info(INFO)
 	l.Warn(WARN)
 	l.Debug(DEBUG)
@@ -99,6 +99,48 @@ func (s *loggerSuite) TestMessages(c *C) {
 	c.Assert((<-lctx.eventsCh).Level, Equals, FATAL)
 }
 
+func (s *loggerSuite) TestLogw(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 2)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
+	l.Infow("Hello", "user", "john", "latency", 12)
+	l.Debugw("Hello", "user", "john")
+	l.Warnw("Odd", "user")
+	go waitThenClose(500, lctx)
+	le, ok := <-lctx.eventsCh
+	c.Assert(ok, Equals, true)
+	c.Assert(le.Payload.(string), Equals, "Hello")
+	c.Assert(le.Level, Equals, INFO)
+	c.Assert(le.Fields, DeepEquals, []Field{{"user", "john"}, {"latency", 12}})
+
+	le, ok = <-lctx.eventsCh
+	c.Assert(ok, Equals, true)
+	c.Assert(le.Level, Equals, WARN)
+	c.Assert(le.Fields, DeepEquals, []Field{{"user", nil}})
+}
+
+func (s *loggerSuite) TestWith(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 3)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
+	child := l.With("requestId", 1)
+	grandChild := child.With("user", "john")
+	child.Info("Hello")
+	grandChild.Infow("Hello", "latency", 12)
+	l.Info("Hello")
+
+	// child loggers share the level with their parent
+	l.setLogLevelSetting(&logLevelSetting{"a", WARN})
+	grandChild.Info("Never delivered")
+	go waitThenClose(500, lctx)
+
+	c.Assert((<-lctx.eventsCh).Fields, DeepEquals, []Field{{"requestId", 1}})
+	le := <-lctx.eventsCh
+	c.Assert(le.LoggerName, Equals, "a")
+	c.Assert(le.Fields, DeepEquals, []Field{{"requestId", 1}, {"user", "john"}, {"latency", 12}})
+	c.Assert(len((<-lctx.eventsCh).Fields), Equals, 0)
+	_, ok := <-lctx.eventsCh
+	c.Assert(ok, Equals, false)
+}
+
 func (s *loggerSuite) TestLevelsOrder(c *C) {
 	ok := FATAL < ERROR && ERROR < WARN && WARN < INFO && INFO < DEBUG && DEBUG < TRACE
 	c.Assert(ok, Equals, true)
@@ -0,0 +1,34 @@
+package log4g
+
+import "context"
+
+// the key the diagnostic fields are stored in context.Context by
+type mdcKey struct{}
+
+func contextWith(ctx context.Context, key string, value interface{}) context.Context {
+	fields := appendFields(contextFields(ctx), []interface{}{key, value})
+	return context.WithValue(ctx, mdcKey{}, fields)
+}
+
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(mdcKey{}).([]Field)
+	return fields
+}
+
+// withContextFields returns the diagnostic fields of ctx followed by the
+// fields. The fields slice is never modified
+func withContextFields(ctx context.Context, fields []Field) []Field {
+	mdc := contextFields(ctx)
+	if len(mdc) == 0 {
+		return fields
+	}
+	if len(fields) == 0 {
+		return mdc
+	}
+	result := make([]Field, 0, len(mdc)+len(fields))
+	result = append(result, mdc...)
+	return append(result, fields...)
+}
@@ -0,0 +1,72 @@
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+)
+
+type mdcSuite struct {
+}
+
+var _ = Suite(&mdcSuite{})
+
+func (s *mdcSuite) TestContextWith(c *C) {
+	ctx := context.Background()
+	c.Assert(ContextFields(ctx), IsNil)
+	c.Assert(ContextFields(nil), IsNil)
+
+	ctx1 := ContextWith(ctx, "traceId", "abc")
+	ctx2 := ContextWith(ctx1, "user", "john")
+	c.Assert(ContextFields(ctx1), DeepEquals, []Field{{"traceId", "abc"}})
+	c.Assert(ContextFields(ctx2), DeepEquals, []Field{{"traceId", "abc"}, {"user", "john"}})
+
+	// the parent context fields are not affected
+	ctx3 := ContextWith(ctx1, "user", "bob")
+	c.Assert(ContextFields(ctx2), DeepEquals, []Field{{"traceId", "abc"}, {"user", "john"}})
+	c.Assert(ContextFields(ctx3), DeepEquals, []Field{{"traceId", "abc"}, {"user", "bob"}})
+}
+
+func (s *mdcSuite) TestWithContextFields(c *C) {
+	fields := []Field{{"k", 1}}
+	c.Assert(withContextFields(nil, fields), DeepEquals, fields)
+	c.Assert(withContextFields(context.Background(), nil), IsNil)
+
+	ctx := ContextWith(context.Background(), "traceId", "abc")
+	c.Assert(withContextFields(ctx, nil), DeepEquals, []Field{{"traceId", "abc"}})
+	c.Assert(withContextFields(ctx, fields), DeepEquals, []Field{{"traceId", "abc"}, {"k", 1}})
+	c.Assert(fields, DeepEquals, []Field{{"k", 1}})
+}
+
+func (s *mdcSuite) TestLogCtx(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 4)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
+	ctx := ContextWith(context.Background(), "traceId", "abc")
+
+	l.InfoCtx(ctx, "Hello")
+	l.With("user", "john").LogwCtx(ctx, WARN, "Hello", "latency", 12)
+	l.DebugCtx(ctx, "Never delivered")
+	l.Info("Hello")
+	go waitThenClose(500, lctx)
+
+	le := <-lctx.eventsCh
+	c.Assert(le.Payload.(string), Equals, "Hello")
+	c.Assert(le.Fields, DeepEquals, []Field{{"traceId", "abc"}})
+	le = <-lctx.eventsCh
+	c.Assert(le.Level, Equals, WARN)
+	c.Assert(le.Fields, DeepEquals, []Field{{"traceId", "abc"}, {"user", "john"}, {"latency", 12}})
+	le = <-lctx.eventsCh
+	c.Assert(le.Level, Equals, INFO)
+	c.Assert(len(le.Fields), Equals, 0)
+	_, ok := <-lctx.eventsCh
+	c.Assert(ok, Equals, false)
+}
+
+func (s *mdcSuite) TestLayoutCtx(c *C) {
+	lctx := &logContext{eventsCh: make(chan *LogEvent, 1)}
+	l := newLogger("a", &loggerState{lctx: lctx, logLevel: INFO})
+	ctx := ContextWith(context.Background(), "traceId", "abc")
+
+	t, _ := ParseLayout("%m trace=%X{traceId}")
+	l.LogfCtx(ctx, INFO, "Hello %s", "World")
+	c.Assert(ToLogMessage(<-lctx.eventsCh, t), Equals, "Hello World trace=abc")
+}
@@ -0,0 +1,216 @@
+package log4g
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// include=<path> directive in a properties file is replaced by the properties
+// of the file with the path. A relative path is relative to the directory of
+// the including file
+const cfgInclude = "include"
+
+// whitespace characters of the properties format
+const propsWhitespace = " \t\f"
+
+// propLocation is the place where a property is defined
+type propLocation struct {
+	fileName string
+	lineNum  int
+}
+
+// loadPropsFile reads properties from the file and the files included by it.
+// If locs is not nil, the places where the properties are defined are put there
+func loadPropsFile(fileName string, locs map[string]propLocation) (map[string]string, error) {
+	props := make(map[string]string)
+	return props, readPropsFile(fileName, props, locs, nil)
+}
+
+// readPropsFile puts properties of the file into props. The later properties
+// override the earlier ones. including contains absolute names of the files
+// which include the file to detect cyclic includes
+func readPropsFile(fileName string, props map[string]string, locs map[string]propLocation, including []string) error {
+	absName, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	for _, name := range including {
+		if name == absName {
+			return errors.New("Cyclic include of the config file " + fileName)
+		}
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	including = append(including, absName)
+	return readProps(f, fileName, func(key, value string, lineNum int) error {
+		if key != cfgInclude {
+			props[key] = value
+			if locs != nil {
+				locs[key] = propLocation{fileName, lineNum}
+			}
+			return nil
+		}
+
+		if !filepath.IsAbs(value) {
+			value = filepath.Join(filepath.Dir(fileName), value)
+		}
+		if err := readPropsFile(value, props, locs, including); err != nil {
+			return errors.New("Cannot include config file in line " + strconv.Itoa(lineNum) + " of the config file " +
+				fileName + ": " + err.Error())
+		}
+		return nil
+	})
+}
+
+// readProps parses properties in Java .properties format and calls onProp for
+// every key-value pair with the number of the line where the pair starts.
+// Unlike Java, trailing whitespaces of values are ignored unless they are escaped.
+func readProps(r io.Reader, fileName string, onProp func(key, value string, lineNum int) error) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimLeft(scanner.Text(), propsWhitespace)
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		startLine := lineNum
+		for isContinued(line) {
+			line = line[:len(line)-1]
+			if !scanner.Scan() {
+				break
+			}
+			lineNum++
+			line += strings.TrimLeft(scanner.Text(), propsWhitespace)
+		}
+
+		key, value, err := splitProp(line)
+		if err != nil {
+			return errors.New("Unexpected value in line " + strconv.Itoa(startLine) + ": \"" + line +
+				"\" in the config file " + fileName + ": " + err.Error())
+		}
+		if err := onProp(key, value, startLine); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// the line is continued by the next one if it ends with odd number of backslashes
+func isContinued(line string) bool {
+	return trailingBackslashes(line)%2 == 1
+}
+
+func trailingBackslashes(s string) int {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n
+}
+
+// splitProp splits the logical line into unescaped key and value. The key is
+// terminated by the first unescaped '=', ':' or whitespace
+func splitProp(line string) (key, value string, err error) {
+	i := 0
+	for i < len(line) && strings.IndexByte("=:"+propsWhitespace, line[i]) < 0 {
+		if line[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i > len(line) {
+		i = len(line)
+	}
+
+	rest := strings.TrimLeft(line[i:], propsWhitespace)
+	if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], propsWhitespace)
+	}
+	// trailing whitespaces are removed unless the last one is escaped
+	for len(rest) > 0 && strings.IndexByte(propsWhitespace, rest[len(rest)-1]) >= 0 &&
+		trailingBackslashes(rest[:len(rest)-1])%2 == 0 {
+		rest = rest[:len(rest)-1]
+	}
+
+	if key, err = unescapeProp(line[:i]); err != nil {
+		return "", "", err
+	}
+	value, err = unescapeProp(rest)
+	return key, value, err
+}
+
+// unescapeProp replaces \t, \n, \r, \f and \uXXXX escape sequences by the
+// characters, other escaped characters stand for themselves
+func unescapeProp(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			r, err := parseUnicodeEscape(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			i += 4
+			// the surrogate pair is encoded by 2 escape sequences
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], "\\u") {
+				if r2, err := parseUnicodeEscape(s[i+3:]); err == nil {
+					if dr := utf16.DecodeRune(r, r2); dr != unicode.ReplacementChar {
+						r = dr
+						i += 6
+					}
+				}
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// parses 4 hex digits of \uXXXX escape sequence
+func parseUnicodeEscape(s string) (rune, error) {
+	if len(s) < 4 {
+		return 0, errors.New("malformed \\uXXXX encoding")
+	}
+	r, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, errors.New("malformed \\uXXXX encoding")
+	}
+	return rune(r), nil
+}
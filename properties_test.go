@@ -0,0 +1,90 @@
+package log4g
+
+import (
+	. "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type propertiesSuite struct {
+}
+
+var _ = Suite(&propertiesSuite{})
+
+func parseProps(c *C, text string) map[string]string {
+	props := map[string]string{}
+	err := readProps(strings.NewReader(text), "test", func(key, value string, lineNum int) error {
+		props[key] = value
+		return nil
+	})
+	c.Assert(err, IsNil)
+	return props
+}
+
+func (s *propertiesSuite) TestReadProps(c *C) {
+	props := parseProps(c, `
+# comment
+  ! another comment
+a=1
+  b : 2  
+c    3
+d
+e=
+f = multi \
+    line \
+    value
+g=trailing\ 
+h\=i\:j\ k=escaped key
+l=\t\u0041\u00e9\ud83d\ude00\\
+m=\#not a comment
+`)
+	c.Assert(props, DeepEquals, map[string]string{
+		"a":       "1",
+		"b":       "2",
+		"c":       "3",
+		"d":       "",
+		"e":       "",
+		"f":       "multi line value",
+		"g":       "trailing ",
+		"h=i:j k": "escaped key",
+		"l":       "\tAé😀\\",
+		"m":       "#not a comment"})
+}
+
+func (s *propertiesSuite) TestReadPropsLines(c *C) {
+	var lines []int
+	err := readProps(strings.NewReader("a=1\n\nb=2\\\n 3\nc=4"), "test", func(key, value string, lineNum int) error {
+		lines = append(lines, lineNum)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(lines, DeepEquals, []int{1, 3, 5})
+
+	err = readProps(strings.NewReader("a=1\nb=\\u12"), "test", func(key, value string, lineNum int) error { return nil })
+	c.Assert(err, ErrorMatches, "Unexpected value in line 2: .* in the config file test: malformed .*")
+}
+
+func (s *propertiesSuite) TestInclude(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(dir, "base"), 0770), IsNil)
+	writeFile(c, filepath.Join(dir, "base", "base.properties"), "a=1\nb=1\ninclude=common.properties\n")
+	writeFile(c, filepath.Join(dir, "base", "common.properties"), "c=1\n")
+	writeFile(c, filepath.Join(dir, "service.properties"), "b=0\ninclude=base/base.properties\nb=2\n")
+
+	props, err := loadPropsFile(filepath.Join(dir, "service.properties"), nil)
+	c.Assert(err, IsNil)
+	c.Assert(props, DeepEquals, map[string]string{"a": "1", "b": "2", "c": "1"})
+
+	writeFile(c, filepath.Join(dir, "base", "common.properties"), "include="+filepath.Join(dir, "service.properties"))
+	_, err = loadPropsFile(filepath.Join(dir, "service.properties"), nil)
+	c.Assert(err, ErrorMatches, "Cannot include config file in line 2 .*Cyclic include .*")
+
+	writeFile(c, filepath.Join(dir, "base", "common.properties"), "include=unknown.properties")
+	_, err = loadPropsFile(filepath.Join(dir, "service.properties"), nil)
+	c.Assert(err, NotNil)
+}
+
+func writeFile(c *C, name, content string) {
+	c.Assert(os.WriteFile(name, []byte(content), 0660), IsNil)
+}
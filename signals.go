@@ -0,0 +1,126 @@
+package log4g
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// fileReopener can be implemented by appenders which write to files, to
+// reopen the files moved by an external tool like logrotate
+type fileReopener interface {
+	reopen(ctx context.Context) error
+}
+
+// the levels set by levelSignal one by one, the next signal restores the
+// levels which were before the cycle
+var signalLevels = []Level{DEBUG, TRACE}
+
+// signalHandler cycles levels of the logger names on levelSignal and
+// reopens the appender files on reopenSignal
+type signalHandler struct {
+	lm          *logManager
+	loggerNames []string
+	sigCh       chan os.Signal
+	stopCh      chan struct{}
+	// the index of the level in signalLevels plus 1, or 0 if the levels
+	// are not changed by the signal
+	step int
+	// the levels of the logger names before the cycle and whether they were
+	// set explicitly
+	levels   []Level
+	explicit []bool
+}
+
+func (lm *logManager) handleSignals(loggerNames []string) error {
+	if levelSignal == nil || reopenSignal == nil {
+		return errors.New("Cannot handle signals: the signals are not supported by the platform")
+	}
+	if len(loggerNames) == 0 {
+		loggerNames = []string{rootLoggerName}
+	}
+	sh := &signalHandler{lm: lm, sigCh: make(chan os.Signal, 1), stopCh: make(chan struct{})}
+	for _, name := range loggerNames {
+		sh.loggerNames = append(sh.loggerNames, normalizeLogName(name))
+	}
+
+	lm.rwLock.Lock()
+	defer lm.rwLock.Unlock()
+
+	if lm.signals != nil {
+		lm.signals.stop()
+	}
+	lm.signals = sh
+	signal.Notify(sh.sigCh, levelSignal, reopenSignal)
+	go sh.run()
+	return nil
+}
+
+func (lm *logManager) reopenFiles() {
+	lm.rwLock.RLock()
+	defer lm.rwLock.RUnlock()
+
+	lm.config.reopenFiles(context.Background())
+}
+
+// reopenFiles makes every appender which implements fileReopener reopen its
+// file. The events appended before the call are written to the previous file
+func (lc *logConfig) reopenFiles(ctx context.Context) error {
+	for _, app := range lc.appenders {
+		if sa, ok := app.(*statAppender); ok {
+			app = sa.Appender
+		}
+		if r, ok := app.(fileReopener); ok {
+			if err := r.reopen(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sh *signalHandler) run() {
+	for {
+		select {
+		case <-sh.stopCh:
+			return
+		case sig := <-sh.sigCh:
+			if sig == reopenSignal {
+				sh.lm.reopenFiles()
+			} else {
+				sh.cycleLevels()
+			}
+		}
+	}
+}
+
+// cycleLevels sets the next level of signalLevels for the logger names, or
+// restores their levels when the cycle is over
+func (sh *signalHandler) cycleLevels() {
+	sh.step = (sh.step + 1) % (len(signalLevels) + 1)
+	if sh.step == 1 {
+		sh.levels = make([]Level, len(sh.loggerNames))
+		sh.explicit = make([]bool, len(sh.loggerNames))
+		for i, name := range sh.loggerNames {
+			sh.levels[i], sh.explicit[i] = sh.lm.getLogLevel(name)
+		}
+	}
+
+	for i, name := range sh.loggerNames {
+		switch {
+		case sh.step > 0:
+			sh.lm.setLogLevel(name, signalLevels[sh.step-1])
+		case sh.explicit[i]:
+			sh.lm.setLogLevel(name, sh.levels[i])
+		default:
+			sh.lm.resetLogLevel(name)
+		}
+	}
+}
+
+// stop stops handling the signals, must be called under the logManager lock
+func (sh *signalHandler) stop() {
+	signal.Stop(sh.sigCh)
+	close(sh.stopCh)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package log4g
+
+import "os"
+
+// the signals are not supported by the platform, so HandleSignals() fails
+var levelSignal os.Signal
+var reopenSignal os.Signal
@@ -0,0 +1,81 @@
+//go:build unix
+
+package log4g
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+type signalsSuite struct {
+}
+
+var _ = Suite(&signalsSuite{})
+
+func sendSignal(c *C, sig syscall.Signal) {
+	c.Assert(syscall.Kill(os.Getpid(), sig), IsNil)
+}
+
+// waits until the signal is processed and the logger level is changed
+func waitLogLevel(c *C, m *logManager, loggerName string, level Level, explicit bool) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if l, e := m.getLogLevel(loggerName); l == level && e == explicit {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	checkLogLevel(c, m, loggerName, level, explicit)
+}
+
+func (s *signalsSuite) TestCycleLevels(c *C) {
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	m.setLogLevel("a", WARN)
+	c.Assert(m.handleSignals([]string{"a", "b"}), IsNil)
+
+	// "b" level is changed after "a" one
+	sendSignal(c, syscall.SIGUSR1)
+	waitLogLevel(c, m, "b", DEBUG, true)
+	checkLogLevel(c, m, "a", DEBUG, true)
+	sendSignal(c, syscall.SIGUSR1)
+	waitLogLevel(c, m, "b", TRACE, true)
+	checkLogLevel(c, m, "a", TRACE, true)
+	sendSignal(c, syscall.SIGUSR1)
+	waitLogLevel(c, m, "b", INFO, false)
+	checkLogLevel(c, m, "a", WARN, true)
+	sendSignal(c, syscall.SIGUSR1)
+	waitLogLevel(c, m, "b", DEBUG, true)
+	checkLogLevel(c, m, "a", DEBUG, true)
+}
+
+func (s *signalsSuite) TestReopenFiles(c *C) {
+	fileName := filepath.Join(c.MkDir(), "app.log")
+	m := newTestLogManager(c)
+	defer m.shutdown(context.Background())
+	c.Assert(m.registerAppender(faFactory), IsNil)
+	c.Assert(m.setNewProperties(map[string]string{"appender.file.type": fileAppenderName,
+		"appender.file.fileName": fileName, "appender.file.layout": "%m", "context.appenders": "file"}), IsNil)
+	c.Assert(m.handleSignals(nil), IsNil)
+
+	m.getLogger("a").Info("before")
+	c.Assert(m.flush(context.Background()), IsNil)
+	c.Assert(os.Rename(fileName, fileName+".1"), IsNil)
+	sendSignal(c, syscall.SIGHUP)
+	// the file is created again when the signal is processed
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if _, err := os.Stat(fileName); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.getLogger("a").Info("after")
+	c.Assert(m.flush(context.Background()), IsNil)
+
+	data, err := os.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "after\n")
+}
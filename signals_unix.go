@@ -0,0 +1,12 @@
+//go:build unix
+
+package log4g
+
+import (
+	"os"
+	"syscall"
+)
+
+// the signals handled by HandleSignals()
+var levelSignal os.Signal = syscall.SIGUSR1
+var reopenSignal os.Signal = syscall.SIGHUP
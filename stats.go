@@ -0,0 +1,135 @@
+package log4g
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// statAppender wraps every appender created by log4g configuration to count
+// its Append() calls and to report its errors
+type statAppender struct {
+	Appender
+	name       string
+	appends    atomic.Uint64
+	errors     atomic.Uint64
+	appendTime atomic.Int64
+	// the last time the error was printed to stderr in nanoseconds
+	lastErrorTime atomic.Int64
+	// set if the appender is the primary one of a failover appender
+	failover atomic.Pointer[failoverHandler]
+}
+
+// failoverHandler is called for the errors, which the primary appender of a
+// failover appender reports after its Append() returned true. It returns
+// true if the event is delivered by the secondary appenders
+type failoverHandler func(event *LogEvent, err error) bool
+
+func newStatAppender(name string, appender Appender) *statAppender {
+	sa := &statAppender{Appender: appender, name: name}
+	if er, ok := appender.(ErrorReporter); ok {
+		er.SetErrorHandler(sa.onError)
+	}
+	return sa
+}
+
+func init() {
+	expvar.Publish("log4g", expvar.Func(func() interface{} { return Stats() }))
+}
+
+func (sa *statAppender) Append(event *LogEvent) bool {
+	start := time.Now()
+	ok := sa.Appender.Append(event)
+	sa.appendTime.Add(int64(time.Since(start)))
+	sa.appends.Add(1)
+	// the failover appender handles the failed Append() itself
+	if !ok && sa.failover.Load() == nil {
+		sa.onError(event, errors.New("the appender could not append the event"))
+	}
+	return ok
+}
+
+// setFailoverHandler sets the handler of the appender errors, nil removes it
+func (sa *statAppender) setFailoverHandler(handler failoverHandler) {
+	if handler == nil {
+		sa.failover.Store(nil)
+		return
+	}
+	sa.failover.Store(&handler)
+}
+
+// onError passes the error to the registered error handler, or prints it to
+// stderr if there is no handler. The errors with no event are not failed
+// over, because no event is lost
+func (sa *statAppender) onError(event *LogEvent, err error) {
+	if h := sa.failover.Load(); h != nil && event != nil && (*h)(event, err) {
+		return
+	}
+	sa.errors.Add(1)
+	if reportError(sa.name, event, err) {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := sa.lastErrorTime.Load()
+	if now-last > int64(time.Minute) && sa.lastErrorTime.CompareAndSwap(last, now) {
+		fmt.Fprintf(os.Stderr, "log4g appender \"%s\": %s\n", sa.name, err)
+	}
+}
+
+// Flusher implementation, which is no-op if the wrapped appender doesn't
+// implement the interface
+func (sa *statAppender) Flush(ctx context.Context) error {
+	if f, ok := sa.Appender.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// queuedCounter implementation
+func (sa *statAppender) queued() int {
+	if q, ok := sa.Appender.(queuedCounter); ok {
+		return q.queued()
+	}
+	return 0
+}
+
+func (lc *logContext) stats() ContextStats {
+	cs := ContextStats{Name: lc.loggerName, QueueDepth: len(lc.eventsCh), Capacity: cap(lc.eventsCh),
+		Enqueued: lc.enqueued.Load(), Dropped: lc.dropped.Load(), Processed: lc.processed.Load(),
+		ProcessingTime: time.Duration(lc.processingTime.Load())}
+	if cs.Processed > 0 {
+		cs.AvgProcessingTime = cs.ProcessingTime / time.Duration(cs.Processed)
+	}
+	return cs
+}
+
+func appenderStats(name string, app Appender) AppenderStats {
+	as := AppenderStats{Name: name}
+	if sa, ok := app.(*statAppender); ok {
+		as.Appends = sa.appends.Load()
+		as.Errors = sa.errors.Load()
+		as.AppendTime = time.Duration(sa.appendTime.Load())
+		app = sa.Appender
+	}
+	if sr, ok := app.(StatsReporter); ok {
+		as.Details = sr.Stats()
+	}
+	return as
+}
+
+func (lc *logConfig) stats() *Statistics {
+	result := &Statistics{}
+	for _, c := range lc.logContexts.Copy() {
+		result.Contexts = append(result.Contexts, c.(*logContext).stats())
+	}
+	for name, app := range lc.appenders {
+		result.Appenders = append(result.Appenders, appenderStats(name, app))
+	}
+	sort.Slice(result.Appenders, func(i, j int) bool { return result.Appenders[i].Name < result.Appenders[j].Name })
+	return result
+}
@@ -0,0 +1,73 @@
+package log4g
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	. "gopkg.in/check.v1"
+)
+
+type statsSuite struct {
+}
+
+var _ = Suite(&statsSuite{})
+
+type failingAppender struct {
+	testAppender
+}
+
+func (fa *failingAppender) Append(event *LogEvent) bool {
+	return false
+}
+
+func (fa *failingAppender) Stats() map[string]int64 {
+	return map[string]int64{"failures": 1}
+}
+
+func (s *statsSuite) TestStats(c *C) {
+	m := newTestLogManager(c)
+	c.Assert(m.setNewProperties(map[string]string{
+		"appender.ROOT.type":  consoleAppenderName,
+		"context.appenders":   "ROOT",
+		"context.a.appenders": "ROOT",
+		"context.a.buffer":    "10"}), IsNil)
+	m.config.appenders["fail"] = newStatAppender("fail", &failingAppender{})
+
+	l := m.getLogger("a")
+	for i := 0; i < 3; i++ {
+		l.Info("m")
+	}
+	c.Assert(m.flush(context.Background()), IsNil)
+	m.config.appenders["fail"].Append(&LogEvent{Level: INFO})
+
+	st := m.stats()
+	c.Assert(len(st.Contexts), Equals, 2)
+	c.Assert(st.Contexts[0].Name, Equals, rootLoggerName)
+	c.Assert(st.Contexts[0].Enqueued, Equals, uint64(0))
+	a := st.Contexts[1]
+	c.Assert(a.Name, Equals, "a")
+	c.Assert(a.Capacity, Equals, 10)
+	c.Assert(a.QueueDepth, Equals, 0)
+	c.Assert(a.Enqueued, Equals, uint64(3))
+	c.Assert(a.Processed, Equals, uint64(3))
+	c.Assert(a.Dropped, Equals, uint64(0))
+	c.Assert(a.AvgProcessingTime, Equals, a.ProcessingTime/3)
+
+	c.Assert(len(st.Appenders), Equals, 2)
+	c.Assert(st.Appenders[0].Name, Equals, "ROOT")
+	c.Assert(st.Appenders[0].Appends, Equals, uint64(3))
+	c.Assert(st.Appenders[0].Errors, Equals, uint64(0))
+	c.Assert(st.Appenders[0].Details, IsNil)
+	c.Assert(st.Appenders[1].Name, Equals, "fail")
+	c.Assert(st.Appenders[1].Appends, Equals, uint64(1))
+	c.Assert(st.Appenders[1].Errors, Equals, uint64(1))
+	c.Assert(st.Appenders[1].Details, DeepEquals, map[string]int64{"failures": 1})
+	m.shutdown(context.Background())
+}
+
+func (s *statsSuite) TestExpvar(c *C) {
+	v := expvar.Get("log4g")
+	c.Assert(v, NotNil)
+	st := &Statistics{}
+	c.Assert(json.Unmarshal([]byte(v.String()), st), IsNil)
+}
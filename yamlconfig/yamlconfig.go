@@ -0,0 +1,76 @@
+// Package yamlconfig allows to configure log4g by YAML documents. Importing the
+// package registers the decoder of .yaml and .yml files, so log4g.ConfigFile(),
+// log4g.ValidateFile() and log4g.WatchConfigF() read them:
+//
+//	import _ "github.com/dspasibenko/log4g/yamlconfig"
+//
+// The document has the same structure as log4g.ConfigJSON() document:
+//
+//	levels:
+//	  11: SEVERE
+//	appenders:
+//	  file: {type: log4g/fileAppender, fileName: app.log, layout: "%p %m"}
+//	contexts:
+//	  "": {appenders: [file], level: INFO}
+//	  a.b: {level: DEBUG, buffer: 1000}
+//	loggers:
+//	  a.b.c: {level: TRACE}
+package yamlconfig
+
+import (
+	"errors"
+	"github.com/dspasibenko/log4g"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+func init() {
+	log4g.RegisterConfigDecoder(Decode, ".yaml", ".yml")
+}
+
+// Config reads log4g configuration from YAML document
+func Config(r io.Reader) error {
+	return log4g.ConfigDoc(r, Decode)
+}
+
+// Decode is log4g.ConfigDecoder of YAML documents. It returns the document in
+// the same form JSON is decoded to, but all scalar values are kept as they
+// are written in the document
+func Decode(r io.Reader) (interface{}, error) {
+	var node yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
+		if err == io.EOF {
+			return map[string]interface{}{}, nil
+		}
+		return nil, errors.New("Cannot parse YAML config: " + err.Error())
+	}
+	return nodeToDoc(&node), nil
+}
+
+func nodeToDoc(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return nodeToDoc(node.Content[0])
+	case yaml.AliasNode:
+		return nodeToDoc(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = nodeToDoc(node.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]interface{}, 0, len(node.Content))
+		for _, n := range node.Content {
+			s = append(s, nodeToDoc(n))
+		}
+		return s
+	}
+	if node.Tag == "!!null" {
+		return nil
+	}
+	return node.Value
+}
@@ -0,0 +1,76 @@
+package yamlconfig
+
+import (
+	"github.com/dspasibenko/log4g"
+	. "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type yamlSuite struct {
+}
+
+var _ = Suite(&yamlSuite{})
+
+func (s *yamlSuite) TestDecode(c *C) {
+	doc, err := Decode(strings.NewReader(`
+levels:
+  11: SEVERE
+appenders:
+  ROOT: &root {type: log4g/consoleAppender, layout: "%p %m"}
+  COPY: *root
+contexts:
+  "":
+    appenders: [ROOT]
+  a.b:
+    level: DEBUG
+    buffer: 1000
+    blocking: false
+    caller: null
+`))
+	c.Assert(err, IsNil)
+	root := map[string]interface{}{"type": "log4g/consoleAppender", "layout": "%p %m"}
+	c.Assert(doc, DeepEquals, map[string]interface{}{
+		"levels":    map[string]interface{}{"11": "SEVERE"},
+		"appenders": map[string]interface{}{"ROOT": root, "COPY": root},
+		"contexts": map[string]interface{}{
+			"":    map[string]interface{}{"appenders": []interface{}{"ROOT"}},
+			"a.b": map[string]interface{}{"level": "DEBUG", "buffer": "1000", "blocking": "false", "caller": nil}}})
+
+	doc, err = Decode(strings.NewReader(""))
+	c.Assert(err, IsNil)
+	c.Assert(doc, DeepEquals, map[string]interface{}{})
+
+	_, err = Decode(strings.NewReader("levels: [a"))
+	c.Assert(err, ErrorMatches, "Cannot parse YAML config: .*")
+}
+
+func (s *yamlSuite) TestConfig(c *C) {
+	defer log4g.Shutdown()
+
+	c.Assert(Config(strings.NewReader("appenders: {ROOT: {type: log4g/consoleAppender, layout: \"%p %m\"}}\n"+
+		"contexts: {'': {appenders: ROOT}}\nloggers: {a: {level: DEBUG}}\n")), IsNil)
+	level, _ := log4g.GetLogLevel("a")
+	c.Assert(level, Equals, log4g.DEBUG)
+
+	err := Config(strings.NewReader("appenders: {ROOT: {type: log4g/consoleAppender, layout: \"%p %m\"}}\n" +
+		"contexts: {'': {appenders: [ROOT, MISSING]}}\n"))
+	c.Assert(err, ErrorMatches, "Incorrect config document element /contexts//appenders: .*MISSING.*")
+
+	fileName := filepath.Join(c.MkDir(), "log4g.YML")
+	c.Assert(os.WriteFile(fileName, []byte("appenders: {ROOT: {type: log4g/consoleAppender, layout: \"%p %m\"}}\n"+
+		"contexts: {'': {appenders: ROOT}}\nloggers: {a: {level: TRACE}}\n"), 0660), IsNil)
+	c.Assert(log4g.ConfigFile(fileName), IsNil)
+	level, _ = log4g.GetLogLevel("a")
+	c.Assert(level, Equals, log4g.TRACE)
+
+	c.Assert(os.WriteFile(fileName, []byte("contexts:\n  \"\": {appenders: [console], level: INFO}\n"), 0660), IsNil)
+	errs := log4g.ValidateFile(fileName)
+	c.Assert(len(errs), Equals, 1)
+	c.Assert(errs[0].Key, Equals, "context.appenders")
+	c.Assert(errs[0].File, Equals, fileName)
+}